@@ -3,24 +3,48 @@ package debug
 import (
 	"bytes"
 	"dolittle.io/kokk/api/utils"
+	"dolittle.io/kokk/diff"
 	"encoding/json"
 	"net/http"
 	"sort"
 )
 
-func NewDebugHandler(input, output Repository) (http.Handler, error) {
+func NewDebugHandler(input, output Repository, results Results, statuses Statuses) (http.Handler, error) {
 	handler := http.NewServeMux()
 
 	list, err := utils.NewTemplateHandler("api/debug/list.html", func(r *http.Request) (any, error) {
-		resources := output.List()
-		ids := make([]string, 0, len(resources))
-		for _, resource := range resources {
-			ids = append(ids, resource.Id)
+		ids := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, repository := range []Repository{input, output} {
+			for _, resource := range repository.List() {
+				if !seen[resource.Id] {
+					seen[resource.Id] = true
+					ids = append(ids, resource.Id)
+				}
+			}
 		}
 		sort.Strings(ids)
 
+		entries := make([]listEntry, 0, len(ids))
+		for _, id := range ids {
+			entry := listEntry{
+				ID: id,
+			}
+			if resource, err := input.Get(id); err == nil {
+				entry.Source = resource.Source
+			}
+			if result, err := results.GetResult(id); err == nil {
+				entry.Outcome = string(result.Outcome)
+			}
+			if status, err := statuses.Get(id); err == nil {
+				entry.Status = string(status.Status)
+			}
+			entries = append(entries, entry)
+		}
+
 		return listData{
-			IDs: ids,
+			Paused:  results.IsPaused(),
+			Entries: entries,
 		}, nil
 	})
 	if err != nil {
@@ -31,12 +55,22 @@ func NewDebugHandler(input, output Repository) (http.Handler, error) {
 		resourceID := r.URL.Path
 
 		inputContent := ""
+		var inputFiles []string
+		inputParent := ""
+		inputRevision := ""
+		inputSource := ""
+		var inputOverrides []string
 		if resource, err := input.Get(resourceID); err == nil {
 			var pretty bytes.Buffer
 			if err := json.Indent(&pretty, resource.Content, "", "  "); err != nil {
 				return nil, err
 			}
 			inputContent = pretty.String()
+			inputFiles = resource.Files
+			inputParent = resource.Parent
+			inputRevision = resource.Revision
+			inputSource = resource.Source
+			inputOverrides = resource.Overrides
 		}
 
 		outputContent := ""
@@ -48,11 +82,38 @@ func NewDebugHandler(input, output Repository) (http.Handler, error) {
 			outputContent = pretty.String()
 		}
 
-		return viewData{
-			ID:            resourceID,
-			InputContent:  inputContent,
-			OutputContent: outputContent,
-		}, nil
+		dryRunContent := ""
+		if resource, err := results.GetDryRun(resourceID); err == nil {
+			var pretty bytes.Buffer
+			if err := json.Indent(&pretty, resource.Content, "", "  "); err != nil {
+				return nil, err
+			}
+			dryRunContent = pretty.String()
+		}
+
+		data := viewData{
+			ID:             resourceID,
+			InputFiles:     inputFiles,
+			InputParent:    inputParent,
+			InputRevision:  inputRevision,
+			InputSource:    inputSource,
+			InputOverrides: inputOverrides,
+			InputContent:   inputContent,
+			OutputContent:  outputContent,
+			DryRunContent:  dryRunContent,
+		}
+
+		if result, err := results.GetResult(resourceID); err == nil {
+			data.Outcome = string(result.Outcome)
+			data.Error = result.Error
+			data.ImmutableFields = result.ImmutableFields
+		}
+		if status, err := statuses.Get(resourceID); err == nil {
+			data.Status = string(status.Status)
+			data.Changes = status.Changes
+		}
+
+		return data, nil
 	})
 	if err != nil {
 		return nil, err
@@ -66,11 +127,30 @@ func NewDebugHandler(input, output Repository) (http.Handler, error) {
 }
 
 type listData struct {
-	IDs []string
+	Paused  bool
+	Entries []listEntry
+}
+
+type listEntry struct {
+	ID      string
+	Outcome string
+	Status  string
+	Source  string
 }
 
 type viewData struct {
-	ID            string
-	InputContent  string
-	OutputContent string
+	ID              string
+	Status          string
+	Changes         []diff.Change
+	Outcome         string
+	Error           string
+	ImmutableFields []string
+	InputFiles      []string
+	InputParent     string
+	InputRevision   string
+	InputSource     string
+	InputOverrides  []string
+	InputContent    string
+	OutputContent   string
+	DryRunContent   string
 }
@@ -1,8 +1,22 @@
 package debug
 
-import "dolittle.io/kokk/resources"
+import (
+	"dolittle.io/kokk/drift"
+	"dolittle.io/kokk/reconcile"
+	"dolittle.io/kokk/resources"
+)
 
 type Repository interface {
 	List() []resources.Resource
 	Get(id string) (*resources.Resource, error)
 }
+
+type Results interface {
+	GetResult(id string) (*reconcile.Result, error)
+	GetDryRun(id string) (*resources.Resource, error)
+	IsPaused() bool
+}
+
+type Statuses interface {
+	Get(id string) (*drift.Entry, error)
+}
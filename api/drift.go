@@ -0,0 +1,16 @@
+package api
+
+import (
+	"dolittle.io/kokk/drift"
+	"net/http"
+)
+
+type DriftLister interface {
+	List() []drift.Entry
+}
+
+func NewDriftHandler(statuses DriftLister) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return statuses.List()
+	})
+}
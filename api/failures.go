@@ -0,0 +1,19 @@
+package api
+
+import (
+	"dolittle.io/kokk/retry"
+	"net/http"
+)
+
+type FailureLister interface {
+	ListFailures() []retry.Failure
+}
+
+func NewFailuresHandler(input, output FailureLister) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return map[string][]retry.Failure{
+			"input":  input.ListFailures(),
+			"output": output.ListFailures(),
+		}
+	})
+}
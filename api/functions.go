@@ -0,0 +1,16 @@
+package api
+
+import (
+	"dolittle.io/kokk/functions"
+	"net/http"
+)
+
+type FunctionLister interface {
+	ListFunctionResults() []functions.Result
+}
+
+func NewFunctionsHandler(functions FunctionLister) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return functions.ListFunctionResults()
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+)
+
+type Pausable interface {
+	Pause()
+	Resume()
+	IsPaused() bool
+}
+
+// NewPauseHandler returns whether the reconciler is paused on GET requests, and pauses or resumes it on POST requests
+// when pause is true or false
+func NewPauseHandler(reconciler Pausable, pause bool) http.HandlerFunc {
+	status := newJSONHandler(func() any {
+		return pauseStatus{
+			Paused: reconciler.IsPaused(),
+		}
+	})
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		switch request.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if pause {
+				reconciler.Pause()
+			} else {
+				reconciler.Resume()
+			}
+		default:
+			writer.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		status(writer, request)
+	}
+}
+
+type pauseStatus struct {
+	Paused bool `json:"paused"`
+}
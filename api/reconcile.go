@@ -0,0 +1,39 @@
+package api
+
+import (
+	"dolittle.io/kokk/reconcile"
+	"encoding/json"
+	"net/http"
+)
+
+type ResultLister interface {
+	ListResults() []reconcile.Result
+	ListManaged() []string
+}
+
+func NewReconcileHandler(results ResultLister) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return results.ListResults()
+	})
+}
+
+func NewInventoryHandler(results ResultLister) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return results.ListManaged()
+	})
+}
+
+func newJSONHandler(get func() any) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		data, err := json.Marshal(get())
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = writer.Write([]byte(err.Error()))
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write(data)
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"dolittle.io/kokk/input"
+	"net/http"
+)
+
+type RevisionProvider interface {
+	Revisions() []input.Revision
+}
+
+func NewRevisionHandler(revision RevisionProvider) http.HandlerFunc {
+	return newJSONHandler(func() any {
+		return revision.Revisions()
+	})
+}
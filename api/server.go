@@ -12,7 +12,7 @@ import (
 	"time"
 )
 
-func NewServer(config *koanf.Koanf, input, output debug.Repository, logger *zerolog.Logger) (*http.Server, error) {
+func NewServer(config *koanf.Koanf, input, output Repository, reconciler Reconciler, statuses Statuses, functions FunctionLister, revision RevisionProvider, logger *zerolog.Logger) (*http.Server, error) {
 	handler := apiHandler{
 		router: http.NewServeMux(),
 		logger: logger,
@@ -31,13 +31,34 @@ func NewServer(config *koanf.Koanf, input, output debug.Repository, logger *zero
 
 	conf := NewConfigHandler(config)
 
-	ui, err := debug.NewDebugHandler(input, output)
+	results := NewReconcileHandler(reconciler)
+	inventory := NewInventoryHandler(reconciler)
+	pause := NewPauseHandler(reconciler, true)
+	resume := NewPauseHandler(reconciler, false)
+
+	drifts := NewDriftHandler(statuses)
+
+	failures := NewFailuresHandler(input, output)
+
+	functionResults := NewFunctionsHandler(functions)
+
+	revisions := NewRevisionHandler(revision)
+
+	ui, err := debug.NewDebugHandler(input, output, reconciler, statuses)
 	if err != nil {
 		return nil, err
 	}
 
 	handler.router.Handle("/", index)
 	handler.router.Handle("/config", conf)
+	handler.router.Handle("/reconcile", results)
+	handler.router.Handle("/reconcile/inventory", inventory)
+	handler.router.Handle("/reconcile/pause", pause)
+	handler.router.Handle("/reconcile/resume", resume)
+	handler.router.Handle("/drift", drifts)
+	handler.router.Handle("/failures", failures)
+	handler.router.Handle("/functions", functionResults)
+	handler.router.Handle("/revision", revisions)
 	handler.router.Handle("/debug/", ui)
 
 	logger.Info().Int("port", handler.config.Port).Msg("API Server configured")
@@ -48,6 +69,22 @@ func NewServer(config *koanf.Koanf, input, output debug.Repository, logger *zero
 	}, nil
 }
 
+type Repository interface {
+	debug.Repository
+	FailureLister
+}
+
+type Reconciler interface {
+	ResultLister
+	Pausable
+	debug.Results
+}
+
+type Statuses interface {
+	DriftLister
+	debug.Statuses
+}
+
 type apiHandler struct {
 	config apiHandlerConfig
 	router *http.ServeMux
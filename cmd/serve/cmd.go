@@ -3,10 +3,15 @@ package serve
 import (
 	"dolittle.io/kokk/api"
 	"dolittle.io/kokk/config"
+	"dolittle.io/kokk/diff"
+	"dolittle.io/kokk/drift"
+	"dolittle.io/kokk/functions"
 	"dolittle.io/kokk/input"
 	"dolittle.io/kokk/kubernetes"
 	"dolittle.io/kokk/output"
+	"dolittle.io/kokk/reconcile"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 // Command is the "kokk serve" command definition
@@ -33,17 +38,47 @@ var Command = &cobra.Command{
 
 		converter := kubernetes.NewResourceConverter(types)
 
+		modes, err := kubernetes.NewModes(config)
+		if err != nil {
+			return err
+		}
+
 		output, err := output.NewKubernetesOutput(config, types, converter, dc, logger)
 		if err != nil {
 			return err
 		}
 
-		input, err := input.NewDirectoryInput(config, converter, logger)
+		patcher, err := kubernetes.NewPatcher(rc, logger)
+		if err != nil {
+			return err
+		}
+
+		source, err := input.NewSources(config, converter, types, patcher, logger)
+		if err != nil {
+			return err
+		}
+
+		input, err := functions.NewPipeline(config, source, converter, logger)
+		if err != nil {
+			return err
+		}
+
+		differ, err := diff.NewDiffer(config)
+		if err != nil {
+			return err
+		}
+
+		reconciler, err := reconcile.NewReconciler(config, input, output, types, modes, differ, dc, logger)
+		if err != nil {
+			return err
+		}
+
+		detector, err := drift.NewDetector(input, output, differ, modes, logger)
 		if err != nil {
 			return err
 		}
 
-		server, err := api.NewServer(config, input, output, logger)
+		server, err := api.NewServer(config, input, output, reconciler, detector, input, source, logger)
 		if err != nil {
 			return err
 		}
@@ -55,6 +90,30 @@ var Command = &cobra.Command{
 func init() {
 	Command.Flags().Int("server.port", 8080, "The port to listen to")
 	Command.Flags().StringSlice("kubernetes.resources", []string{"Namespace", "Deployment"}, "The Kubernetes resource types to operate on")
+	Command.Flags().String("kubernetes.mode", "report", "How to react when a resource in the cluster diverges from the input, 'enforce', 'report' or 'ignore'")
+	Command.Flags().StringToString("kubernetes.modes", nil, "Per resource type overrides of 'kubernetes.mode', e.g. Namespace=enforce")
 	Command.Flags().Int("kubernetes.resync", 60, "The Kubernetes informer resync interval")
-	Command.Flags().String("input.directory", "./test_input", "The input directory to read from") // TODO: Handle input sources
+	Command.Flags().Int("reconcile.timeout", 30, "The timeout in seconds for each request to the Kubernetes API server while reconciling")
+	Command.Flags().Bool("dry-run", false, "Send all writes to the Kubernetes API server as dry-run requests without persisting them")
+	config.BindFlagToKey(Command.Flags(), "dry-run", "reconcile.dryRun")
+	Command.Flags().Bool("reconcile.prune", true, "Delete managed resources from the cluster when they are removed from the input")
+	Command.Flags().String("reconcile.propagationPolicy", "Background", "The propagation policy to use when pruning resources, 'Background', 'Foreground' or 'Orphan'")
+	Command.Flags().StringSlice("reconcile.recreate", nil, "The Kubernetes resource types to delete and create again when an apply changes immutable fields")
+	Command.Flags().Int("reconcile.recreateTimeout", 120, "The timeout in seconds to wait for a resource to be deleted before it is created again")
+	Command.Flags().Int("retry.maxRetries", 10, "The number of times a failed operation is retried before it is marked as failed")
+	Command.Flags().Duration("retry.baseDelay", 500*time.Millisecond, "The delay before the first retry of a failed operation, doubled for every retry")
+	Command.Flags().Duration("retry.maxDelay", 5*time.Minute, "The maximum delay between retries of a failed operation")
+	Command.Flags().String("input.directory", "./test_input", "The input directory to read from, when 'input.sources' is not configured")
+	Command.Flags().String("input.git.repository", "", "A local git repository to read the input files from instead of 'input.directory'")
+	Command.Flags().String("input.git.ref", "HEAD", "The branch, tag or commit in the git repository to read the input files from")
+	Command.Flags().String("input.git.directory", ".", "The directory within the git repository to read the input files from")
+	Command.Flags().Bool("input.git.fetch", false, "Fetch from the remotes of the git repository before resolving the ref")
+	Command.Flags().Duration("input.git.interval", 30*time.Second, "The interval to poll the git repository for new commits")
+	Command.Flags().String("input.archive.directory", ".", "The directory within archive sources to read the input files from")
+	Command.Flags().Duration("input.archive.interval", 30*time.Second, "The interval to poll archive sources for changes")
+	Command.Flags().StringSlice("input.include", nil, "Glob patterns of the input files to load, all files are loaded if none are given")
+	Command.Flags().StringSlice("input.exclude", []string{"*.md", "*.swp", "*~", ".*"}, "Glob patterns of the input files and directories to ignore")
+	Command.Flags().StringSlice("input.valueFiles", nil, "YAML files with variables for templated input files, overriding 'input.variables'")
+	Command.Flags().Int("input.keepGenerations", 1, "The number of previous generations of generated ConfigMaps and Secrets to keep before they are pruned")
+	Command.Flags().Bool("input.strict", false, "Fail templated input files that reference variables that are not defined")
 }
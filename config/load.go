@@ -6,8 +6,17 @@ import (
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// KeyAnnotation is the flag annotation used to load a flag into a configuration key that differs from its name
+const KeyAnnotation = "kokk.dolittle.io/key"
+
+// BindFlagToKey makes LoadConfigFor load the value of the named flag into the supplied configuration key
+func BindFlagToKey(flags *pflag.FlagSet, name, key string) {
+	_ = flags.SetAnnotation(name, KeyAnnotation, []string{key})
+}
+
 // LoadConfigFor loads the configuration using the given cobra.Command flags,
 // and any supplied YAML files through '--config' arguments
 func LoadConfigFor(cmd *cobra.Command) (*koanf.Koanf, error) {
@@ -20,7 +29,14 @@ func LoadConfigFor(cmd *cobra.Command) (*koanf.Koanf, error) {
 		}
 	}
 
-	if err := k.Load(posflag.Provider(cmd.Flags(), k.Delim(), k), nil); err != nil {
+	flags := posflag.ProviderWithFlag(cmd.Flags(), k.Delim(), k, func(flag *pflag.Flag) (string, interface{}) {
+		key := flag.Name
+		if keys := flag.Annotations[KeyAnnotation]; len(keys) > 0 {
+			key = keys[0]
+		}
+		return key, posflag.FlagVal(cmd.Flags(), flag)
+	})
+	if err := k.Load(flags, nil); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,21 @@
+package diff
+
+// Operation describes how a field differs between the desired and the live object.
+type Operation string
+
+const (
+	// Added means the field is only present in the live object
+	Added Operation = "added"
+	// Removed means the field is only present in the desired object
+	Removed Operation = "removed"
+	// Changed means the field is present in both objects with different values
+	Changed Operation = "changed"
+)
+
+// Change describes a single difference between the desired and the live object, located by a JSON pointer.
+type Change struct {
+	Operation Operation `json:"op"`
+	Path      string    `json:"path"`
+	Desired   any       `json:"desired,omitempty"`
+	Live      any       `json:"live,omitempty"`
+}
@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/koanf"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultIgnoredPaths are the JSON pointers to fields populated by the API server that are never compared
+var DefaultIgnoredPaths = []string{
+	"/status",
+	"/metadata/managedFields",
+	"/metadata/resourceVersion",
+	"/metadata/uid",
+	"/metadata/generation",
+	"/metadata/creationTimestamp",
+	"/metadata/selfLink",
+}
+
+type Differ struct {
+	ignored []ignoreRule
+}
+
+type ignoreRule struct {
+	Group string   `koanf:"group"`
+	Kind  string   `koanf:"kind"`
+	Paths []string `koanf:"paths"`
+}
+
+// NewDiffer creates a Differ that ignores the DefaultIgnoredPaths, and the extra paths per GroupVersionKind configured
+// in 'diff.ignore'. A rule without a group matches all groups, and the kind '*' matches all kinds.
+func NewDiffer(config *koanf.Koanf) (*Differ, error) {
+	differ := &Differ{}
+
+	if err := config.Unmarshal("diff.ignore", &differ.ignored); err != nil {
+		return nil, err
+	}
+
+	return differ, nil
+}
+
+// Diff compares the desired object to the live object, and returns the list of changes sorted by path. Fields that
+// are only present in a map in the live object are considered server defaults and are not reported.
+func (d *Differ) Diff(desired, live *unstructured.Unstructured) []Change {
+	changes := make([]Change, 0)
+	compare(desired.Object, live.Object, nil, d.ignoredPathsFor(desired.GroupVersionKind()), &changes)
+	return changes
+}
+
+func (d *Differ) ignoredPathsFor(gvk schema.GroupVersionKind) [][]string {
+	patterns := make([][]string, 0, len(DefaultIgnoredPaths))
+	for _, path := range DefaultIgnoredPaths {
+		patterns = append(patterns, parsePointer(path))
+	}
+
+	for _, rule := range d.ignored {
+		if rule.Group != "" && rule.Group != gvk.Group {
+			continue
+		}
+		if rule.Kind != "*" && !strings.EqualFold(rule.Kind, gvk.Kind) {
+			continue
+		}
+		for _, path := range rule.Paths {
+			patterns = append(patterns, parsePointer(path))
+		}
+	}
+
+	return patterns
+}
+
+func compare(desired, live any, path []string, ignored [][]string, changes *[]Change) {
+	if isIgnored(path, ignored) {
+		return
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]any:
+		liveValue, ok := live.(map[string]any)
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(desiredValue))
+		for key := range desiredValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := appendSegment(path, key)
+			if _, found := liveValue[key]; !found {
+				if !isIgnored(fieldPath, ignored) {
+					*changes = append(*changes, Change{Operation: Removed, Path: formatPointer(fieldPath), Desired: desiredValue[key]})
+				}
+				continue
+			}
+			compare(desiredValue[key], liveValue[key], fieldPath, ignored, changes)
+		}
+		return
+	case []any:
+		liveValue, ok := live.([]any)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < len(desiredValue) || i < len(liveValue); i++ {
+			itemPath := appendSegment(path, strconv.Itoa(i))
+			switch {
+			case i >= len(liveValue):
+				if !isIgnored(itemPath, ignored) {
+					*changes = append(*changes, Change{Operation: Removed, Path: formatPointer(itemPath), Desired: desiredValue[i]})
+				}
+			case i >= len(desiredValue):
+				if !isIgnored(itemPath, ignored) {
+					*changes = append(*changes, Change{Operation: Added, Path: formatPointer(itemPath), Live: liveValue[i]})
+				}
+			default:
+				compare(desiredValue[i], liveValue[i], itemPath, ignored, changes)
+			}
+		}
+		return
+	default:
+		if valuesEqual(desired, live) {
+			return
+		}
+	}
+
+	*changes = append(*changes, Change{Operation: Changed, Path: formatPointer(path), Desired: desired, Live: live})
+}
+
+func isIgnored(path []string, ignored [][]string) bool {
+	for _, pattern := range ignored {
+		if matchesPattern(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendSegment(path []string, segment string) []string {
+	extended := make([]string, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, segment)
+}
+
+// valuesEqual compares two scalar values, treating integer and floating point numbers with the same value as equal
+func valuesEqual(a, b any) bool {
+	if aNumber, ok := toFloat(a); ok {
+		if bNumber, ok := toFloat(b); ok {
+			return aNumber == bNumber
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch number := value.(type) {
+	case int64:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
@@ -0,0 +1,143 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired any
+		live    any
+		ignored []string
+		changes []Change
+	}{
+		{
+			name:    "equal maps",
+			desired: map[string]any{"a": "x", "b": map[string]any{"c": true}},
+			live:    map[string]any{"a": "x", "b": map[string]any{"c": true}},
+		},
+		{
+			name:    "server defaults in live maps",
+			desired: map[string]any{"spec": map[string]any{"replicas": int64(1)}},
+			live:    map[string]any{"spec": map[string]any{"replicas": int64(1), "revisionHistoryLimit": int64(10)}},
+		},
+		{
+			name:    "field missing from live",
+			desired: map[string]any{"data": map[string]any{"a": "x", "b": "y"}},
+			live:    map[string]any{"data": map[string]any{"a": "x"}},
+			changes: []Change{{Operation: Removed, Path: "/data/b", Desired: "y"}},
+		},
+		{
+			name:    "changed value",
+			desired: map[string]any{"data": map[string]any{"a": "x"}},
+			live:    map[string]any{"data": map[string]any{"a": "z"}},
+			changes: []Change{{Operation: Changed, Path: "/data/a", Desired: "x", Live: "z"}},
+		},
+		{
+			name:    "changes sorted by key",
+			desired: map[string]any{"b": "1", "a": "1", "c": "1"},
+			live:    map[string]any{"b": "2", "a": "2", "c": "1"},
+			changes: []Change{
+				{Operation: Changed, Path: "/a", Desired: "1", Live: "2"},
+				{Operation: Changed, Path: "/b", Desired: "1", Live: "2"},
+			},
+		},
+		{
+			name:    "integer and float with the same value",
+			desired: map[string]any{"replicas": int64(2), "port": 80, "ratio": float64(1)},
+			live:    map[string]any{"replicas": float64(2), "port": int64(80), "ratio": int64(1)},
+		},
+		{
+			name:    "integer and float with different values",
+			desired: map[string]any{"replicas": int64(2)},
+			live:    map[string]any{"replicas": float64(2.5)},
+			changes: []Change{{Operation: Changed, Path: "/replicas", Desired: int64(2), Live: float64(2.5)}},
+		},
+		{
+			name:    "number and string",
+			desired: map[string]any{"port": int64(80)},
+			live:    map[string]any{"port": "80"},
+			changes: []Change{{Operation: Changed, Path: "/port", Desired: int64(80), Live: "80"}},
+		},
+		{
+			name:    "lists compared by index",
+			desired: map[string]any{"args": []any{"a", "b"}},
+			live:    map[string]any{"args": []any{"b", "a"}},
+			changes: []Change{
+				{Operation: Changed, Path: "/args/0", Desired: "a", Live: "b"},
+				{Operation: Changed, Path: "/args/1", Desired: "b", Live: "a"},
+			},
+		},
+		{
+			name:    "item missing from live list",
+			desired: map[string]any{"args": []any{"a", "b"}},
+			live:    map[string]any{"args": []any{"a"}},
+			changes: []Change{{Operation: Removed, Path: "/args/1", Desired: "b"}},
+		},
+		{
+			name:    "extra item in live list",
+			desired: map[string]any{"args": []any{"a"}},
+			live:    map[string]any{"args": []any{"a", "b"}},
+			changes: []Change{{Operation: Added, Path: "/args/1", Live: "b"}},
+		},
+		{
+			name:    "maps within lists",
+			desired: map[string]any{"containers": []any{map[string]any{"name": "a", "image": "x:1"}}},
+			live:    map[string]any{"containers": []any{map[string]any{"name": "a", "image": "x:2", "imagePullPolicy": "Always"}}},
+			changes: []Change{{Operation: Changed, Path: "/containers/0/image", Desired: "x:1", Live: "x:2"}},
+		},
+		{
+			name:    "different types",
+			desired: map[string]any{"value": map[string]any{"a": "x"}},
+			live:    map[string]any{"value": []any{"a"}},
+			changes: []Change{{Operation: Changed, Path: "/value", Desired: map[string]any{"a": "x"}, Live: []any{"a"}}},
+		},
+		{
+			name:    "ignored paths",
+			desired: map[string]any{"metadata": map[string]any{"uid": "1", "name": "a"}, "status": map[string]any{"ready": true}},
+			live:    map[string]any{"metadata": map[string]any{"uid": "2", "name": "a"}},
+			ignored: []string{"/metadata/uid", "/status"},
+		},
+		{
+			name:    "ignored paths with wildcards",
+			desired: map[string]any{"containers": []any{map[string]any{"image": "x:1", "name": "a"}, map[string]any{"image": "y:1", "name": "b"}}},
+			live:    map[string]any{"containers": []any{map[string]any{"image": "x:2", "name": "a"}, map[string]any{"image": "y:2", "name": "c"}}},
+			ignored: []string{"/containers/*/image"},
+			changes: []Change{{Operation: Changed, Path: "/containers/1/name", Desired: "b", Live: "c"}},
+		},
+		{
+			name:    "ignored list items",
+			desired: map[string]any{"args": []any{"a"}},
+			live:    map[string]any{"args": []any{"a", "b"}},
+			ignored: []string{"/args/1"},
+		},
+		{
+			name:    "escaped keys",
+			desired: map[string]any{"annotations": map[string]any{"example.com/a~b": "x"}},
+			live:    map[string]any{"annotations": map[string]any{}},
+			changes: []Change{{Operation: Removed, Path: "/annotations/example.com~1a~0b", Desired: "x"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ignored := make([][]string, 0, len(test.ignored))
+			for _, pointer := range test.ignored {
+				ignored = append(ignored, parsePointer(pointer))
+			}
+
+			changes := make([]Change, 0)
+			compare(test.desired, test.live, nil, ignored, &changes)
+
+			expected := test.changes
+			if expected == nil {
+				expected = []Change{}
+			}
+			if !reflect.DeepEqual(changes, expected) {
+				t.Errorf("compare returned %+v, expected %+v", changes, expected)
+			}
+		})
+	}
+}
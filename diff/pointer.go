@@ -0,0 +1,45 @@
+package diff
+
+import "strings"
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// formatPointer formats the path segments as an RFC 6901 JSON pointer
+func formatPointer(path []string) string {
+	var builder strings.Builder
+	for _, segment := range path {
+		builder.WriteString("/")
+		builder.WriteString(pointerEscaper.Replace(segment))
+	}
+	return builder.String()
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped path segments
+func parsePointer(pointer string) []string {
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = pointerUnescaper.Replace(segment)
+	}
+	return segments
+}
+
+// matchesPattern checks whether the path is equal to, or below, the pattern. A '*' segment in the pattern matches any
+// single segment in the path.
+func matchesPattern(path, pattern []string) bool {
+	if len(path) < len(pattern) {
+		return false
+	}
+	for i, segment := range pattern {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		path    []string
+	}{
+		{pointer: "", path: nil},
+		{pointer: "/status", path: []string{"status"}},
+		{pointer: "/metadata/labels", path: []string{"metadata", "labels"}},
+		{pointer: "/spec/containers/0/image", path: []string{"spec", "containers", "0", "image"}},
+		{pointer: "/metadata/annotations/example.com~1name", path: []string{"metadata", "annotations", "example.com/name"}},
+		{pointer: "/data/a~0b", path: []string{"data", "a~b"}},
+		{pointer: "/data/~01", path: []string{"data", "~1"}},
+		{pointer: "/data/~10", path: []string{"data", "/0"}},
+		{pointer: "/spec/*/name", path: []string{"spec", "*", "name"}},
+		{pointer: "/data/", path: []string{"data", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			if path := parsePointer(test.pointer); !reflect.DeepEqual(path, test.path) {
+				t.Errorf("parsePointer(%q) = %q, expected %q", test.pointer, path, test.path)
+			}
+		})
+	}
+}
+
+func TestFormatPointer(t *testing.T) {
+	tests := []struct {
+		path    []string
+		pointer string
+	}{
+		{path: nil, pointer: ""},
+		{path: []string{"metadata", "labels"}, pointer: "/metadata/labels"},
+		{path: []string{"spec", "containers", "0"}, pointer: "/spec/containers/0"},
+		{path: []string{"metadata", "annotations", "example.com/name"}, pointer: "/metadata/annotations/example.com~1name"},
+		{path: []string{"data", "a~b"}, pointer: "/data/a~0b"},
+		{path: []string{"data", "~1"}, pointer: "/data/~01"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			if pointer := formatPointer(test.path); pointer != test.pointer {
+				t.Errorf("formatPointer(%q) = %q, expected %q", test.path, pointer, test.pointer)
+			}
+			if len(test.path) > 0 {
+				if path := parsePointer(test.pointer); !reflect.DeepEqual(path, test.path) {
+					t.Errorf("parsePointer(%q) = %q, expected %q", test.pointer, path, test.path)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    []string
+		pattern []string
+		matches bool
+	}{
+		{name: "equal", path: []string{"metadata", "uid"}, pattern: []string{"metadata", "uid"}, matches: true},
+		{name: "below", path: []string{"status", "conditions", "0"}, pattern: []string{"status"}, matches: true},
+		{name: "above", path: []string{"metadata"}, pattern: []string{"metadata", "uid"}, matches: false},
+		{name: "sibling", path: []string{"metadata", "name"}, pattern: []string{"metadata", "uid"}, matches: false},
+		{name: "prefix of segment", path: []string{"statuses"}, pattern: []string{"status"}, matches: false},
+		{name: "wildcard", path: []string{"spec", "containers", "1", "image"}, pattern: []string{"spec", "containers", "*", "image"}, matches: true},
+		{name: "wildcard below", path: []string{"spec", "containers", "1", "image", "tag"}, pattern: []string{"spec", "containers", "*", "image"}, matches: true},
+		{name: "wildcard sibling", path: []string{"spec", "containers", "1", "name"}, pattern: []string{"spec", "containers", "*", "image"}, matches: false},
+		{name: "wildcard needs a segment", path: []string{"spec", "containers"}, pattern: []string{"spec", "containers", "*"}, matches: false},
+		{name: "empty pattern", path: []string{"spec"}, pattern: nil, matches: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matches := matchesPattern(test.path, test.pattern); matches != test.matches {
+				t.Errorf("matchesPattern(%q, %q) = %t, expected %t", test.path, test.pattern, matches, test.matches)
+			}
+		})
+	}
+}
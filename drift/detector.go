@@ -0,0 +1,149 @@
+package drift
+
+import (
+	"sort"
+	"sync"
+
+	"dolittle.io/kokk/diff"
+	"dolittle.io/kokk/kubernetes"
+	"dolittle.io/kokk/resources"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type Repository interface {
+	List() []resources.Resource
+	Get(id string) (*resources.Resource, error)
+	AddListener(listener resources.Listener)
+}
+
+type Differ interface {
+	Diff(desired, live *unstructured.Unstructured) []diff.Change
+}
+
+type ModeProvider interface {
+	ModeFor(gvk schema.GroupVersionKind) kubernetes.Mode
+}
+
+type Detector struct {
+	input   Repository
+	output  Repository
+	differ  Differ
+	modes   ModeProvider
+	entries map[string]Entry
+	mutex   sync.RWMutex
+	logger  *zerolog.Logger
+}
+
+func NewDetector(input, output Repository, differ Differ, modes ModeProvider, logger *zerolog.Logger) (*Detector, error) {
+	loggerWithComponent := logger.With().Str("component", "drift").Logger()
+
+	detector := &Detector{
+		input:   input,
+		output:  output,
+		differ:  differ,
+		modes:   modes,
+		entries: make(map[string]Entry),
+		logger:  &loggerWithComponent,
+	}
+
+	input.AddListener(detector)
+	output.AddListener(detector)
+
+	for _, repository := range []Repository{input, output} {
+		for _, resource := range repository.List() {
+			detector.update(resource.Id)
+		}
+	}
+
+	return detector, nil
+}
+
+func (d *Detector) Get(id string) (*Entry, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if entry, found := d.entries[id]; found {
+		return &entry, nil
+	}
+
+	return nil, StatusNotFound
+}
+
+func (d *Detector) List() []Entry {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	list := make([]Entry, 0, len(d.entries))
+	for _, entry := range d.entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+func (d *Detector) OnResourceUpdated(id string) {
+	d.update(id)
+}
+
+func (d *Detector) OnResourceRemoved(id string) {
+	d.update(id)
+}
+
+func (d *Detector) update(id string) {
+	logger := d.logger.With().Str("method", "update").Str("id", id).Logger()
+
+	entry, found, err := d.compare(id)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to compare resource")
+		return
+	}
+
+	d.mutex.Lock()
+	previous := d.entries[id]
+	if found {
+		d.entries[id] = entry
+	} else {
+		delete(d.entries, id)
+	}
+	d.mutex.Unlock()
+
+	if previous.Status != entry.Status {
+		logger.Debug().Str("status", string(entry.Status)).Str("previous", string(previous.Status)).Msg("Drift status changed")
+	}
+}
+
+func (d *Detector) compare(id string) (Entry, bool, error) {
+	input, inputErr := d.input.Get(id)
+	output, outputErr := d.output.Get(id)
+
+	switch {
+	case inputErr != nil && outputErr != nil:
+		return Entry{}, false, nil
+	case inputErr != nil:
+		return Entry{Id: id, Status: NotInInput}, true, nil
+	case outputErr != nil:
+		return Entry{Id: id, Status: MissingInCluster}, true, nil
+	}
+
+	desired := &unstructured.Unstructured{}
+	if err := desired.UnmarshalJSON(input.Content); err != nil {
+		return Entry{}, false, err
+	}
+	live := &unstructured.Unstructured{}
+	if err := live.UnmarshalJSON(output.Content); err != nil {
+		return Entry{}, false, err
+	}
+
+	changes := d.differ.Diff(desired, live)
+	if len(changes) == 0 {
+		return Entry{Id: id, Status: InSync}, true, nil
+	}
+	if d.modes.ModeFor(live.GroupVersionKind()) == kubernetes.Ignore {
+		return Entry{Id: id, Status: Ignored}, true, nil
+	}
+	return Entry{Id: id, Status: Drifted, Changes: changes}, true, nil
+}
@@ -0,0 +1,7 @@
+package drift
+
+import "errors"
+
+var (
+	StatusNotFound = errors.New("status not found")
+)
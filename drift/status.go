@@ -0,0 +1,21 @@
+package drift
+
+import "dolittle.io/kokk/diff"
+
+// Status describes how a resource in the input compares to the object in the cluster.
+type Status string
+
+const (
+	InSync           Status = "InSync"
+	Drifted          Status = "Drifted"
+	MissingInCluster Status = "MissingInCluster"
+	NotInInput       Status = "NotInInput"
+	Ignored          Status = "Ignored"
+)
+
+// Entry records the drift Status of a resource, and the changes between the input and the cluster when Drifted.
+type Entry struct {
+	Id      string        `json:"id"`
+	Status  Status        `json:"status"`
+	Changes []diff.Change `json:"changes,omitempty"`
+}
@@ -0,0 +1,10 @@
+package functions
+
+import "errors"
+
+var (
+	ResourceNotFound = errors.New("resource not found")
+	FunctionFailed   = errors.New("function failed")
+	InvalidOutput    = errors.New("function returned an invalid ResourceList")
+	InvalidFunction  = errors.New("invalid function configuration")
+)
@@ -0,0 +1,117 @@
+package functions
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// ResourceListAPIVersion is the API version of the KRM ResourceList passed to and from functions
+	ResourceListAPIVersion = "config.kubernetes.io/v1"
+	// ResourceListKind is the kind of the KRM ResourceList passed to and from functions
+	ResourceListKind = "ResourceList"
+	// DefaultTimeout is the timeout of a function run when no timeout is configured
+	DefaultTimeout = 30 * time.Second
+)
+
+// Function is an external executable that transforms a KRM ResourceList read from stdin, and writes the transformed
+// ResourceList to stdout
+type Function struct {
+	Name    string         `koanf:"name"`
+	Exec    string         `koanf:"exec"`
+	Args    []string       `koanf:"args"`
+	Timeout string         `koanf:"timeout"`
+	Config  map[string]any `koanf:"config"`
+}
+
+type resourceList struct {
+	APIVersion     string           `json:"apiVersion"`
+	Kind           string           `json:"kind"`
+	Items          []map[string]any `json:"items"`
+	FunctionConfig map[string]any   `json:"functionConfig,omitempty"`
+	Results        []resultItem     `json:"results,omitempty"`
+}
+
+type resultItem struct {
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+func (f *Function) timeout() (time.Duration, error) {
+	if f.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	return time.ParseDuration(f.Timeout)
+}
+
+// run executes the function with the items, and returns the transformed items and the messages reported by the
+// function. Everything the function writes to stderr is logged.
+func (f *Function) run(items []map[string]any, logger *zerolog.Logger) ([]map[string]any, []string, error) {
+	timeout, err := f.timeout()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input, err := json.Marshal(resourceList{
+		APIVersion:     ResourceListAPIVersion,
+		Kind:           ResourceListKind,
+		Items:          items,
+		FunctionConfig: f.Config,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, f.Exec, f.Args...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err = command.Run()
+
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		logger.Info().Str("stream", "stderr").Msg(scanner.Text())
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, nil, fmt.Errorf("%w: timed out after %s", FunctionFailed, timeout)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", FunctionFailed, err)
+	}
+
+	output := resourceList{}
+	if err := yaml.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", InvalidOutput, err)
+	}
+	if output.Kind != ResourceListKind {
+		return nil, nil, fmt.Errorf("%w: kind is %q", InvalidOutput, output.Kind)
+	}
+
+	messages := make([]string, 0, len(output.Results))
+	failed := false
+	for _, result := range output.Results {
+		messages = append(messages, fmt.Sprintf("%s: %s", result.Severity, result.Message))
+		if result.Severity == "error" {
+			failed = true
+		}
+	}
+	if failed {
+		return nil, messages, fmt.Errorf("%w: reported errors in results", FunctionFailed)
+	}
+
+	return output.Items, messages, nil
+}
@@ -0,0 +1,268 @@
+package functions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// pipelineKey is the single key queued whenever the input changes, so that changes are batched into one run
+const pipelineKey = "pipeline"
+
+type Repository interface {
+	List() []resources.Resource
+	Get(id string) (*resources.Resource, error)
+	AddListener(listener resources.Listener)
+	HasSynced() bool
+	Synced() <-chan struct{}
+	HasFailed() bool
+	ListFailures() []retry.Failure
+}
+
+type TypeConverter interface {
+	Convert(object *unstructured.Unstructured) (*resources.Resource, error)
+}
+
+// Pipeline is a repository of the resources from an input repository, transformed by the chain of functions
+// configured in 'input.functions'. Every change in the input runs the whole chain again, and a failed run is retried
+// while the resources from the last successful run are kept. The pipeline does not run until the input has synced, and
+// the repository is empty until the first successful run.
+type Pipeline struct {
+	input      Repository
+	converter  TypeConverter
+	functions  []Function
+	repository map[string]resources.Resource
+	results    map[string]Result
+	listeners  []resources.Listener
+	synced     *resources.SyncSignal
+	queue      *retry.Queue
+	mutex      sync.RWMutex
+	logger     *zerolog.Logger
+}
+
+func NewPipeline(config *koanf.Koanf, input Repository, converter TypeConverter, logger *zerolog.Logger) (*Pipeline, error) {
+	loggerWithComponent := logger.With().Str("component", "functions").Logger()
+
+	pipeline := &Pipeline{
+		input:     input,
+		converter: converter,
+		results:   make(map[string]Result),
+		synced:    resources.NewSyncSignal(),
+		queue:     retry.NewQueue(config, "functions", &loggerWithComponent),
+		logger:    &loggerWithComponent,
+	}
+
+	if err := config.Unmarshal("input.functions", &pipeline.functions); err != nil {
+		return nil, err
+	}
+	for _, function := range pipeline.functions {
+		if function.Name == "" || function.Exec == "" {
+			return nil, fmt.Errorf("%w: functions require a name and exec", InvalidFunction)
+		}
+		if _, err := function.timeout(); err != nil {
+			return nil, fmt.Errorf("function %s: %w", function.Name, err)
+		}
+	}
+
+	input.AddListener(pipeline)
+	go pipeline.queue.Run(pipeline.process)
+	go pipeline.runWhenSynced()
+
+	return pipeline, nil
+}
+
+func (p *Pipeline) Get(id string) (*resources.Resource, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if resource, found := p.repository[id]; found {
+		return &resource, nil
+	}
+
+	return nil, ResourceNotFound
+}
+
+func (p *Pipeline) List() []resources.Resource {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	list := make([]resources.Resource, 0, len(p.repository))
+	for _, resource := range p.repository {
+		list = append(list, resource)
+	}
+	return list
+}
+
+// AddListener registers a resources.Listener that is notified when resources in the repository change
+func (p *Pipeline) AddListener(listener resources.Listener) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.listeners = append(p.listeners, listener)
+}
+
+// HasSynced returns true when the pipeline has run successfully at least once after the input repository has synced
+func (p *Pipeline) HasSynced() bool {
+	return p.synced.HasSynced()
+}
+
+// Synced returns a channel that is closed when the pipeline has synced
+func (p *Pipeline) Synced() <-chan struct{} {
+	return p.synced.Synced()
+}
+
+// HasFailed returns true when the input repository has failed
+func (p *Pipeline) HasFailed() bool {
+	return p.input.HasFailed()
+}
+
+// ListFailures returns the failures of the input repository, and of the pipeline runs
+func (p *Pipeline) ListFailures() []retry.Failure {
+	return append(p.input.ListFailures(), p.queue.ListFailures()...)
+}
+
+// ListFunctionResults returns the result of the last run of each function, in the order they are configured
+func (p *Pipeline) ListFunctionResults() []Result {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	list := make([]Result, 0, len(p.functions))
+	for _, function := range p.functions {
+		if result, found := p.results[function.Name]; found {
+			list = append(list, result)
+		}
+	}
+	return list
+}
+
+func (p *Pipeline) OnResourceUpdated(_ string) {
+	p.queue.Add(pipelineKey)
+}
+
+func (p *Pipeline) OnResourceRemoved(_ string) {
+	p.queue.Add(pipelineKey)
+}
+
+// runWhenSynced waits for the input to sync, and then queues the first run
+func (p *Pipeline) runWhenSynced() {
+	<-p.input.Synced()
+	p.queue.Add(pipelineKey)
+}
+
+func (p *Pipeline) process(keys []string) map[string]error {
+	if !p.input.HasSynced() {
+		p.logger.Trace().Msg("Input has not synced, skipping run")
+		return nil
+	}
+	if err := p.run(); err != nil {
+		return map[string]error{pipelineKey: err}
+	}
+	return nil
+}
+
+// run passes the input resources through the functions, and replaces the repository with the result
+func (p *Pipeline) run() error {
+	logger := p.logger.With().Str("method", "run").Logger()
+
+	inputs := p.input.List()
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].Id < inputs[j].Id
+	})
+
+	items := make([]map[string]any, 0, len(inputs))
+	for _, resource := range inputs {
+		item := make(map[string]any)
+		if err := json.Unmarshal(resource.Content, &item); err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+
+	for _, function := range p.functions {
+		functionLogger := logger.With().Str("function", function.Name).Logger()
+		started := time.Now()
+		transformed, messages, err := function.run(items, &functionLogger)
+
+		result := Result{
+			Function:  function.Name,
+			Succeeded: err == nil,
+			Messages:  messages,
+			Duration:  time.Since(started),
+			Timestamp: time.Now(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		p.mutex.Lock()
+		p.results[function.Name] = result
+		p.mutex.Unlock()
+
+		if err != nil {
+			functionLogger.Error().Err(err).Strs("messages", messages).Msg("Function failed")
+			return fmt.Errorf("function %s: %w", function.Name, err)
+		}
+		functionLogger.Debug().Dur("duration", result.Duration).Int("items", len(transformed)).Msg("Function succeeded")
+		items = transformed
+	}
+
+	repository := make(map[string]resources.Resource, len(items))
+	for _, item := range items {
+		converted, err := p.converter.Convert(&unstructured.Unstructured{Object: item})
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to convert resource returned by functions")
+			return err
+		}
+		if _, found := repository[converted.Id]; found {
+			err := fmt.Errorf("%w: resource %s is returned more than once", InvalidOutput, converted.Id)
+			logger.Error().Err(err).Msg("Failed to convert resource returned by functions")
+			return err
+		}
+		if original, err := p.input.Get(converted.Id); err == nil {
+			converted.Files = original.Files
+			converted.Parent = original.Parent
+			converted.Revision = original.Revision
+			converted.Source = original.Source
+			converted.Overrides = original.Overrides
+		}
+		repository[converted.Id] = *converted
+	}
+
+	p.replace(repository)
+	return nil
+}
+
+// replace swaps the repository, and notifies the listeners of the resources that changed
+func (p *Pipeline) replace(repository map[string]resources.Resource) {
+	p.mutex.Lock()
+	previous := p.repository
+	p.repository = repository
+	listeners := p.listeners
+	p.mutex.Unlock()
+	p.synced.MarkSynced()
+
+	for id, resource := range repository {
+		if existing, found := previous[id]; found && bytes.Equal(existing.Content, resource.Content) {
+			continue
+		}
+		for _, listener := range listeners {
+			listener.OnResourceUpdated(id)
+		}
+	}
+	for id := range previous {
+		if _, found := repository[id]; found {
+			continue
+		}
+		for _, listener := range listeners {
+			listener.OnResourceRemoved(id)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package functions
+
+import "time"
+
+// Result is the outcome of the last run of a function in the pipeline
+type Result struct {
+	Function  string        `json:"function"`
+	Succeeded bool          `json:"succeeded"`
+	Error     string        `json:"error,omitempty"`
+	Messages  []string      `json:"messages,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
@@ -0,0 +1,96 @@
+package input
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+)
+
+// ArchiveInput loads the input files from a .tar, .tar.gz, .tgz or .zip archive. The archive is polled for changes
+// every 'input.archive.interval', and the files in a changed archive are loaded all at once. The revision of the
+// loaded files is the SHA-256 hash of the archive.
+type ArchiveInput struct {
+	*snapshotSource
+	path   string
+	logger *zerolog.Logger
+}
+
+func NewArchiveInput(config *koanf.Koanf, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*ArchiveInput, error) {
+	path := config.String("input.archive.path")
+	loggerWithArchive := logger.With().Str("archive", path).Logger()
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	source, err := newSnapshotSource(config, ArchiveSource, Revision{Source: path}, config.String("input.archive.directory"), converter, types, patcher, &loggerWithArchive)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &ArchiveInput{
+		snapshotSource: source,
+		path:           path,
+		logger:         &loggerWithArchive,
+	}
+
+	source.start(archive, config.Duration("input.archive.interval"))
+
+	return archive, nil
+}
+
+// latest returns the hash of the archive
+func (ai *ArchiveInput) latest() (string, error) {
+	logger := ai.logger.With().Str("method", "latest").Logger()
+
+	hash, err := ai.hash()
+	if err != nil {
+		logger.Error().Err(err).Msg("Could not read archive")
+		return "", err
+	}
+	return hash, nil
+}
+
+func (ai *ArchiveInput) hash() (string, error) {
+	file, err := os.Open(ai.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extract writes the files in the archive to the checkout directory
+func (ai *ArchiveInput) extract(_, checkout string) error {
+	if strings.HasSuffix(ai.path, ".zip") {
+		return extractZip(ai.path, checkout)
+	}
+
+	file, err := os.Open(ai.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(ai.path, ".tar.gz") && !strings.HasSuffix(ai.path, ".tgz") {
+		return extractTar(file, checkout)
+	}
+
+	decompressed, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	return extractTar(decompressed, checkout)
+}
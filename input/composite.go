@@ -0,0 +1,173 @@
+package input
+
+import (
+	"sort"
+	"sync"
+
+	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"github.com/rs/zerolog"
+)
+
+// NamedSource is an input source with a name and a priority
+type NamedSource struct {
+	Name     string
+	Priority int
+	Source   Source
+}
+
+// CompositeInput combines the resources of multiple input sources into one repository. When more than one source has
+// a resource with the same ID, the resource from the source with the highest priority is used, and the earliest
+// configured source wins ties.
+type CompositeInput struct {
+	sources   []NamedSource
+	listeners []resources.Listener
+	synced    *resources.SyncSignal
+	mutex     sync.RWMutex
+	logger    *zerolog.Logger
+}
+
+func NewCompositeInput(sources []NamedSource, logger *zerolog.Logger) *CompositeInput {
+	sorted := make([]NamedSource, len(sources))
+	copy(sorted, sources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	composite := &CompositeInput{
+		sources: sorted,
+		synced:  resources.NewSyncSignal(),
+		logger:  logger,
+	}
+
+	for _, source := range sorted {
+		source.Source.AddListener(composite)
+	}
+	go composite.waitForSources()
+
+	return composite
+}
+
+// Get returns the resource from the source with the highest priority, with the names of the sources it overrides
+func (ci *CompositeInput) Get(id string) (*resources.Resource, error) {
+	var winner *resources.Resource
+	for _, source := range ci.sources {
+		resource, err := source.Source.Get(id)
+		if err != nil {
+			continue
+		}
+		if winner == nil {
+			winner = resource
+			winner.Source = source.Name
+			winner.Overrides = nil
+			continue
+		}
+		winner.Overrides = append(winner.Overrides, source.Name)
+	}
+
+	if winner == nil {
+		return nil, ResourceNotFound
+	}
+	return winner, nil
+}
+
+func (ci *CompositeInput) List() []resources.Resource {
+	seen := make(map[string]bool)
+	list := make([]resources.Resource, 0)
+	for _, source := range ci.sources {
+		for _, resource := range source.Source.List() {
+			if seen[resource.Id] {
+				continue
+			}
+			seen[resource.Id] = true
+			if winner, err := ci.Get(resource.Id); err == nil {
+				list = append(list, *winner)
+			}
+		}
+	}
+	return list
+}
+
+// AddListener registers a resources.Listener that is notified when resources in the repository change
+func (ci *CompositeInput) AddListener(listener resources.Listener) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	ci.listeners = append(ci.listeners, listener)
+}
+
+// HasSynced returns true when all the sources have synced
+func (ci *CompositeInput) HasSynced() bool {
+	return ci.synced.HasSynced()
+}
+
+// Synced returns a channel that is closed when all the sources have synced
+func (ci *CompositeInput) Synced() <-chan struct{} {
+	return ci.synced.Synced()
+}
+
+// waitForSources marks the input as synced once all the sources have synced
+func (ci *CompositeInput) waitForSources() {
+	for _, source := range ci.sources {
+		<-source.Source.Synced()
+	}
+	ci.synced.MarkSynced()
+}
+
+// HasFailed returns true when any of the sources has failed
+func (ci *CompositeInput) HasFailed() bool {
+	for _, source := range ci.sources {
+		if source.Source.HasFailed() {
+			return true
+		}
+	}
+	return false
+}
+
+// ListFailures returns the failures of all the sources, with keys prefixed by the source name
+func (ci *CompositeInput) ListFailures() []retry.Failure {
+	list := make([]retry.Failure, 0)
+	for _, source := range ci.sources {
+		for _, failure := range source.Source.ListFailures() {
+			failure.Key = source.Name + ": " + failure.Key
+			list = append(list, failure)
+		}
+	}
+	return list
+}
+
+// Revisions returns the revision of the files loaded by each source, in order of precedence
+func (ci *CompositeInput) Revisions() []Revision {
+	list := make([]Revision, 0, len(ci.sources))
+	for _, source := range ci.sources {
+		revision := source.Source.Revision()
+		revision.Name = source.Name
+		revision.Priority = source.Priority
+		list = append(list, revision)
+	}
+	return list
+}
+
+func (ci *CompositeInput) OnResourceUpdated(id string) {
+	ci.notify(id)
+}
+
+func (ci *CompositeInput) OnResourceRemoved(id string) {
+	ci.notify(id)
+}
+
+// notify tells the listeners that the resource is updated if any source still has it, or removed otherwise
+func (ci *CompositeInput) notify(id string) {
+	ci.mutex.RLock()
+	listeners := ci.listeners
+	ci.mutex.RUnlock()
+
+	_, err := ci.Get(id)
+	for _, listener := range listeners {
+		if err == nil {
+			listener.OnResourceUpdated(id)
+		} else {
+			listener.OnResourceRemoved(id)
+		}
+	}
+}
@@ -2,61 +2,134 @@ package input
 
 import (
 	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"fmt"
 	"github.com/fsnotify/fsnotify"
 	"github.com/knadh/koanf"
 	"github.com/rs/zerolog"
-	"io/ioutil"
+	"io/fs"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/util/yaml"
 	"os"
-	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
+// dataLink is the symlink that kubelet swaps to atomically update the files of mounted ConfigMaps and Secrets
+const dataLink = "..data"
+
 type TypeConverter interface {
+	GetIdFor(object *unstructured.Unstructured) (string, error)
 	Convert(object *unstructured.Unstructured) (*resources.Resource, error)
 }
 
 type DirectoryInput struct {
-	path       string
-	watcher    *fsnotify.Watcher
-	converter  TypeConverter
-	repository map[string]resources.Resource
-	fileIDs    map[string]string
-	logger     *zerolog.Logger
+	path         string
+	watcher      *fsnotify.Watcher
+	filter       *fileFilter
+	converter    TypeConverter
+	patcher      Patcher
+	renderer     *templateRenderer
+	transformers []fileTransformer
+	names        *nameTransformers
+	repository   map[string]resources.Resource
+	files        map[string][]ingredient
+	patches      map[string][]*jsonPatch
+	sources      map[string]string
+	history      map[string][]ingredient
+	generations  int
+	revision     string
+	unsynced     map[string]struct{}
+	failed       map[string]struct{}
+	synced       *resources.SyncSignal
+	queue        *retry.Queue
+	listeners    []resources.Listener
+	mutex        sync.RWMutex
+	logger       *zerolog.Logger
 }
 
-func NewDirectoryInput(config *koanf.Koanf, converter TypeConverter, logger *zerolog.Logger) (*DirectoryInput, error) {
-	watcher, err := fsnotify.NewWatcher()
+func NewDirectoryInput(config *koanf.Koanf, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*DirectoryInput, error) {
+	path := config.String("input.directory")
+
+	input, err := newDirectoryInput(config, path, converter, types, patcher, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	path := config.String("input.directory")
-	if err := watcher.Add(path); err != nil {
+	input.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
 		return nil, err
 	}
 
-	loggerWithPath := logger.With().Str("path", path).Logger()
-
-	input := &DirectoryInput{
-		path:       path,
-		watcher:    watcher,
-		converter:  converter,
-		repository: make(map[string]resources.Resource),
-		fileIDs:    make(map[string]string),
-		logger:     &loggerWithPath,
+	input.unsynced = make(map[string]struct{})
+	input.failed = make(map[string]struct{})
+	if err := input.watchDirectory(path); err != nil {
+		return nil, err
 	}
+	input.mutex.Lock()
+	input.checkSynced()
+	input.mutex.Unlock()
+
+	input.queue.OnGiveUp(input.giveUp)
 
 	go input.listenForChanges()
+	go input.queue.Run(input.processFiles)
 
-	if err := input.createEventsForExistingFiles(); err != nil {
+	return input, nil
+}
+
+// newDirectoryInput creates a DirectoryInput for the path that does not load or watch any files
+func newDirectoryInput(config *koanf.Koanf, path string, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*DirectoryInput, error) {
+	filter, err := newFileFilter(config)
+	if err != nil {
 		return nil, err
 	}
 
-	return input, nil
+	renderer, err := newTemplateRenderer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := newLabelPolicies(config)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := newNameTransformers(config, types)
+	if err != nil {
+		return nil, err
+	}
+
+	loggerWithPath := logger.With().Str("path", path).Logger()
+
+	return &DirectoryInput{
+		path:      path,
+		filter:    filter,
+		converter: converter,
+		patcher:   patcher,
+		renderer:  renderer,
+		transformers: []fileTransformer{
+			policies,
+			names,
+		},
+		names:       names,
+		repository:  make(map[string]resources.Resource),
+		files:       make(map[string][]ingredient),
+		patches:     make(map[string][]*jsonPatch),
+		sources:     make(map[string]string),
+		history:     make(map[string][]ingredient),
+		generations: config.Int("input.keepGenerations"),
+		synced:      resources.NewSyncSignal(),
+		queue:       retry.NewQueue(config, "input", &loggerWithPath),
+		logger:      &loggerWithPath,
+	}, nil
 }
 
 func (di *DirectoryInput) Get(id string) (*resources.Resource, error) {
+	di.mutex.RLock()
+	defer di.mutex.RUnlock()
+
 	if resource, found := di.repository[id]; found {
 		return &resource, nil
 	}
@@ -65,6 +138,9 @@ func (di *DirectoryInput) Get(id string) (*resources.Resource, error) {
 }
 
 func (di *DirectoryInput) List() []resources.Resource {
+	di.mutex.RLock()
+	defer di.mutex.RUnlock()
+
 	list := make([]resources.Resource, 0, len(di.repository))
 	for _, resource := range di.repository {
 		list = append(list, resource)
@@ -72,54 +148,500 @@ func (di *DirectoryInput) List() []resources.Resource {
 	return list
 }
 
-func (di *DirectoryInput) onFileUpdated(name string) {
-	logger := di.logger.With().Str("method", "onFileUpdated").Str("file", name).Logger()
+// ListFailures returns the input files that failed to load and are being retried, or have been given up on
+func (di *DirectoryInput) ListFailures() []retry.Failure {
+	return di.queue.ListFailures()
+}
+
+// HasSynced returns true when all the files found in the directory at startup have been loaded successfully, or given
+// up on. Until then, resources that are missing from the repository might just not have been loaded yet.
+func (di *DirectoryInput) HasSynced() bool {
+	return di.synced.HasSynced()
+}
+
+// Synced returns a channel that is closed when the input has synced
+func (di *DirectoryInput) Synced() <-chan struct{} {
+	return di.synced.Synced()
+}
+
+// HasFailed returns true when files found in the directory at startup were given up on, and have not loaded since.
+// Resources that are missing from the repository might be in those files.
+func (di *DirectoryInput) HasFailed() bool {
+	di.mutex.RLock()
+	defer di.mutex.RUnlock()
+
+	return len(di.failed) > 0
+}
+
+// AddListener registers a resources.Listener that is notified when resources in the repository change
+func (di *DirectoryInput) AddListener(listener resources.Listener) {
+	di.mutex.Lock()
+	defer di.mutex.Unlock()
+
+	di.listeners = append(di.listeners, listener)
+}
+
+// processFiles loads or removes the changed files depending on whether they still exist. Changes to files that are
+// read by generator ingredients reload the generator instead, and new directories are watched.
+func (di *DirectoryInput) processFiles(keys []string) map[string]error {
+	errs := make(map[string]error)
+	for _, key := range keys {
+		name := key
+		di.mutex.RLock()
+		if generator, found := di.sources[key]; found {
+			name = generator
+		}
+		di.mutex.RUnlock()
+
+		if isDataLink(name) {
+			if err := di.reloadAfterSwap(name); err != nil {
+				errs[key] = err
+			}
+			continue
+		}
+
+		info, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			if !di.excludes(name) {
+				di.onFileRemoved(name)
+			}
+			continue
+		}
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		if info.IsDir() {
+			if err := di.watchDirectory(name); err != nil {
+				errs[key] = err
+			}
+			continue
+		}
+		if !di.includes(name) {
+			continue
+		}
+		if err := di.onFileUpdated(name); err != nil {
+			errs[key] = err
+		}
+	}
+
+	di.mutex.Lock()
+	for _, key := range keys {
+		if _, failed := errs[key]; !failed {
+			delete(di.unsynced, key)
+			delete(di.failed, key)
+		}
+	}
+	di.checkSynced()
+	di.mutex.Unlock()
+
+	return errs
+}
+
+// checkSynced marks the input as synced once all the files found in the directory at startup have been loaded or given
+// up on, and stops tracking the files that are found later. The mutex must be held.
+func (di *DirectoryInput) checkSynced() {
+	if di.unsynced != nil && len(di.unsynced) == 0 {
+		di.unsynced = nil
+		di.synced.MarkSynced()
+	}
+}
+
+// giveUp stops waiting for a file found at startup that failed to load too many times, and marks the input as failed
+// until the file loads
+func (di *DirectoryInput) giveUp(key string) {
+	logger := di.logger.With().Str("method", "giveUp").Str("file", key).Logger()
+
+	di.mutex.Lock()
+	defer di.mutex.Unlock()
+
+	if _, found := di.unsynced[key]; !found {
+		return
+	}
+	delete(di.unsynced, key)
+	di.failed[key] = struct{}{}
+	di.checkSynced()
+	logger.Warn().Msg("Gave up loading file found at startup, pruning is disabled until it loads")
+}
+
+func (di *DirectoryInput) onFileUpdated(name string) error {
+	ingredients, patches, sources, err := di.load(name)
+	if err != nil {
+		return err
+	}
+
+	di.setSources(name, sources)
+	return di.replaceIngredients(name, ingredients, patches)
+}
+
+// load reads and parses the file, and returns the ingredients and JSON Patches it contains and the other files that
+// were read to generate them
+func (di *DirectoryInput) load(name string) ([]ingredient, []*jsonPatch, []string, error) {
+	logger := di.logger.With().Str("method", "load").Str("file", name).Logger()
 
 	contents, err := os.ReadFile(name)
 	if err != nil {
 		logger.Error().Err(err).Msg("Could not read input file")
-		return
+		return nil, nil, nil, err
 	}
 
-	resource := unstructured.Unstructured{}
-	if err := yaml.Unmarshal(contents, &resource.Object); err != nil {
-		logger.Error().Err(err).Msg("Could not parse input file as Unstructured")
-		return
+	if isTemplate(name) {
+		contents, err = di.renderer.render(name, contents)
+		if err != nil {
+			logger.Error().Err(err).Msg("Could not render input file template")
+			return nil, nil, nil, err
+		}
 	}
 
-	gvk := resource.GroupVersionKind()
-	logger = logger.With().Str("group", gvk.Group).Str("version", gvk.Version).Str("kind", gvk.Kind).Logger()
-
-	converted, err := di.converter.Convert(&resource)
+	objects, err := parseDocuments(contents)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to convert resource")
-		return
+		logger.Error().Err(err).Msg("Could not parse input file as Unstructured")
+		return nil, nil, nil, err
 	}
 
-	if _, exists := di.repository[converted.Id]; exists {
-		if di.fileIDs[name] != converted.Id {
-			logger.Warn().Str("id", converted.Id).Msg("Resource already described in another file, skipping")
-			return
+	ingredients := make([]ingredient, 0, len(objects))
+	patches := make([]*jsonPatch, 0)
+	sources := make([]string, 0)
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+		logger := logger.With().Str("group", gvk.Group).Str("version", gvk.Version).Str("kind", gvk.Kind).Str("name", object.GetName()).Logger()
+
+		if isKokkKind(object) && object.GetKind() == JSONPatchKind {
+			patch, err := parseJSONPatch(name, object)
+			if err != nil {
+				logger.Error().Err(err).Msg("Could not parse JSON Patch")
+				return nil, nil, nil, err
+			}
+			logger.Trace().Msg("Loaded JSON Patch from file")
+			patches = append(patches, patch)
+			continue
+		}
+
+		expanded, parent, expandedSources, err := expand(name, object)
+		if err != nil {
+			logger.Error().Err(err).Msg("Could not expand Kokk ingredient")
+			return nil, nil, nil, err
+		}
+		sources = append(sources, expandedSources...)
+
+		for _, object := range expanded {
+			original := referencedName(object)
+			if err := di.transform(name, object); err != nil {
+				logger.Error().Err(err).Msg("Failed to transform resource")
+				return nil, nil, nil, err
+			}
+
+			id, err := di.converter.GetIdFor(object)
+			if err != nil {
+				logger.Error().Err(err).Msg("Failed to get id for resource")
+				return nil, nil, nil, err
+			}
+
+			loaded := ingredient{file: name, id: id, object: object, parent: parent}
+			if referencedName(object) != original {
+				loaded.renamedFrom = original
+			}
+			ingredients = append(ingredients, loaded)
+			logger.Trace().Str("id", id).Msg("Loaded ingredient from file")
 		}
 	}
 
-	di.repository[converted.Id] = *converted
-	di.fileIDs[name] = converted.Id
-	logger.Trace().Str("id", converted.Id).Msg("Added resource to repository")
+	return ingredients, patches, sources, nil
 }
 
+// onFileRemoved removes the ingredients loaded from the file, or from all files within it if it was a directory
 func (di *DirectoryInput) onFileRemoved(name string) {
-	logger := di.logger.With().Str("method", "onFileUpdated").Str("file", name).Logger()
+	logger := di.logger.With().Str("method", "onFileRemoved").Str("file", name).Logger()
 
-	id, found := di.fileIDs[name]
-	if !found {
+	di.mutex.RLock()
+	removed := make([]string, 0)
+	for _, loaded := range di.loadedFiles() {
+		if loaded == name || strings.HasPrefix(loaded, name+string(filepath.Separator)) {
+			removed = append(removed, loaded)
+		}
+	}
+	di.mutex.RUnlock()
+
+	if len(removed) == 0 {
 		logger.Warn().Msg("File was not already loaded, ignoring")
 		return
 	}
 
-	delete(di.repository, id)
-	delete(di.fileIDs, id)
-	logger.Trace().Str("id", id).Msg("Removed resource from repository")
+	for _, file := range removed {
+		logger.Trace().Str("removed", file).Msg("Removed ingredients from repository")
+		di.setSources(file, nil)
+		_ = di.replaceIngredients(file, nil, nil)
+	}
+}
+
+// loadedFiles returns the names of the files that ingredients or JSON Patches are loaded from. Must be called while
+// holding the mutex.
+func (di *DirectoryInput) loadedFiles() []string {
+	files := make([]string, 0, len(di.files)+len(di.patches))
+	for file := range di.files {
+		files = append(files, file)
+	}
+	for file := range di.patches {
+		files = append(files, file)
+	}
+	return files
+}
+
+// setSources records the files that were read by the generator ingredient loaded from the file
+func (di *DirectoryInput) setSources(name string, sources []string) {
+	di.mutex.Lock()
+	defer di.mutex.Unlock()
+
+	for source, generator := range di.sources {
+		if generator == name {
+			delete(di.sources, source)
+		}
+	}
+	for _, source := range sources {
+		di.sources[source] = name
+	}
+}
+
+// replaceIngredients replaces the ingredients and JSON Patches loaded from the file, and rebuilds the affected resources
+func (di *DirectoryInput) replaceIngredients(name string, ingredients []ingredient, patches []*jsonPatch) error {
+	di.mutex.Lock()
+	previous := di.files[name]
+	_, wasPatch := di.patches[name]
+	if len(ingredients) > 0 {
+		di.files[name] = ingredients
+	} else {
+		delete(di.files, name)
+	}
+	if len(patches) > 0 {
+		di.patches[name] = patches
+	} else {
+		delete(di.patches, name)
+	}
+	generated := di.keepGenerations(previous, ingredients)
+	di.mutex.Unlock()
+
+	if wasPatch || len(patches) > 0 || generated || isRenamed(previous) || isRenamed(ingredients) {
+		return di.rebuildAll()
+	}
+
+	ids := make([]string, 0, len(previous)+len(ingredients))
+	seen := make(map[string]bool)
+	for _, ingredient := range append(previous, ingredients...) {
+		if !seen[ingredient.id] {
+			seen[ingredient.id] = true
+			ids = append(ids, ingredient.id)
+		}
+	}
+
+	var firstErr error
+	for _, id := range ids {
+		if err := di.rebuild(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// isRenamed returns true if any of the ingredients are renamed, which requires rebuilding the resources that reference
+// them
+func isRenamed(ingredients []ingredient) bool {
+	for _, ingredient := range ingredients {
+		if ingredient.renamedFrom != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// keepGenerations moves the generated ConfigMaps and Secrets that are replaced by a new generation to the history of
+// their generator, keeping the configured number of previous generations. It returns true if any of the ingredients
+// are generated, which requires rebuilding the resources that reference them. Must be called while holding the mutex.
+func (di *DirectoryInput) keepGenerations(previous, ingredients []ingredient) bool {
+	current := make(map[string]bool)
+	generators := make(map[string]bool)
+	generated := false
+	for _, loaded := range ingredients {
+		if _, ok := generatedNameOf(loaded.object); ok {
+			current[loaded.id] = true
+			generators[loaded.parent] = true
+			generated = true
+		}
+	}
+
+	for _, replaced := range previous {
+		if _, ok := generatedNameOf(replaced.object); !ok {
+			continue
+		}
+		generated = true
+		if current[replaced.id] {
+			continue
+		}
+		if !generators[replaced.parent] {
+			delete(di.history, replaced.parent)
+			continue
+		}
+		di.history[replaced.parent] = append([]ingredient{replaced}, di.history[replaced.parent]...)
+	}
+
+	for parent, generations := range di.history {
+		kept := make([]ingredient, 0, len(generations))
+		for _, generation := range generations {
+			if !current[generation.id] && len(kept) < di.generations {
+				kept = append(kept, generation)
+			}
+		}
+		if len(kept) > 0 {
+			di.history[parent] = kept
+		} else {
+			delete(di.history, parent)
+		}
+	}
+	return generated
+}
+
+// rebuild merges all the ingredient files that describe the resource ID, and stores the result in the repository. If
+// no files describe the resource anymore, it is removed from the repository.
+func (di *DirectoryInput) rebuild(id string) error {
+	logger := di.logger.With().Str("method", "rebuild").Str("id", id).Logger()
+
+	di.mutex.Lock()
+	ingredients := make([]ingredient, 0)
+	names := make(map[objectName]string)
+	renamed := make(map[objectName][]renamedObject)
+	for _, fileIngredients := range di.files {
+		for _, ingredient := range fileIngredients {
+			if ingredient.id == id {
+				ingredients = append(ingredients, ingredient)
+			}
+			if name, ok := generatedNameOf(ingredient.object); ok {
+				names[name] = ingredient.object.GetName()
+			}
+			if ingredient.renamedFrom != "" {
+				if file, err := di.relativePath(ingredient.file); err == nil {
+					object := ingredient.object
+					name := objectName{kind: object.GetKind(), namespace: object.GetNamespace(), name: ingredient.renamedFrom}
+					renamed[name] = append(renamed[name], renamedObject{file: file, name: referencedName(object)})
+				}
+			}
+		}
+	}
+	for _, generations := range di.history {
+		for _, generation := range generations {
+			if generation.id == id {
+				ingredients = append(ingredients, generation)
+			}
+		}
+	}
+	patches := di.sortedPatches()
+	revision := di.revision
+	listeners := di.listeners
+
+	if len(ingredients) == 0 {
+		delete(di.repository, id)
+		di.mutex.Unlock()
+		logger.Trace().Msg("Removed resource from repository")
+
+		for _, listener := range listeners {
+			listener.OnResourceRemoved(id)
+		}
+		return nil
+	}
+	di.mutex.Unlock()
+
+	merged, files, parent, err := merge(ingredients, di.patcher)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to merge ingredients")
+		return err
+	}
+
+	for _, patch := range patches {
+		if !patch.matches(id, merged) {
+			continue
+		}
+		if merged, err = patch.apply(merged); err != nil {
+			logger.Error().Err(err).Msg("Failed to apply JSON Patch")
+			return err
+		}
+		files = append(files, patch.file)
+	}
+	if file, err := di.relativePath(files[0]); err == nil {
+		renameReferences(merged, di.names.renamesFor(file, renamed))
+	}
+	rewriteReferences(merged, names)
+
+	converted, err := di.converter.Convert(merged)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to convert resource")
+		return err
+	}
+	if converted.Id != id {
+		err := fmt.Errorf("the merged resource has id %s: %w", converted.Id, IdentityChanged)
+		logger.Error().Err(err).Msg("Failed to convert resource")
+		return err
+	}
+	converted.Files = files
+	converted.Parent = parent
+	converted.Revision = revision
+
+	di.mutex.Lock()
+	di.repository[id] = *converted
+	di.mutex.Unlock()
+	logger.Trace().Strs("files", files).Msg("Added resource to repository")
+
+	for _, listener := range listeners {
+		listener.OnResourceUpdated(id)
+	}
+	return nil
+}
+
+// rebuildAll rebuilds every resource described by the loaded files, or already in the repository
+func (di *DirectoryInput) rebuildAll() error {
+	di.mutex.RLock()
+	ids := make(map[string]struct{})
+	for _, ingredients := range di.files {
+		for _, ingredient := range ingredients {
+			ids[ingredient.id] = struct{}{}
+		}
+	}
+	for _, generations := range di.history {
+		for _, generation := range generations {
+			ids[generation.id] = struct{}{}
+		}
+	}
+	for id := range di.repository {
+		ids[id] = struct{}{}
+	}
+	di.mutex.RUnlock()
+
+	var firstErr error
+	for id := range ids {
+		if err := di.rebuild(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sortedPatches returns the loaded JSON Patches sorted by filename, and in the order they appear within each file.
+// Must be called while holding the mutex.
+func (di *DirectoryInput) sortedPatches() []*jsonPatch {
+	patches := make([]*jsonPatch, 0, len(di.patches))
+	for _, filePatches := range di.patches {
+		patches = append(patches, filePatches...)
+	}
+	sort.SliceStable(patches, func(i, j int) bool {
+		return patches[i].file < patches[j].file
+	})
+	return patches
+}
+
+// relativePath returns the path of the file relative to the input directory
+func (di *DirectoryInput) relativePath(name string) (string, error) {
+	relative, err := filepath.Rel(di.path, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relative), nil
 }
 
 func (di *DirectoryInput) listenForChanges() {
@@ -134,11 +656,17 @@ func (di *DirectoryInput) listenForChanges() {
 			if !ok {
 				return
 			}
-			if event.Op == fsnotify.Create || event.Op == fsnotify.Write {
-				di.onFileUpdated(event.Name)
-			} else if event.Op == fsnotify.Remove || event.Op == fsnotify.Rename {
-				di.onFileRemoved(event.Name)
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if isDataLink(event.Name) {
+				di.queue.Add(event.Name)
+				continue
 			}
+			if relative, err := di.relativePath(event.Name); err == nil && isAtomicWriterInternal(relative) {
+				continue
+			}
+			di.queue.Add(event.Name)
 		case err, ok := <-di.watcher.Errors:
 			if !ok {
 				return
@@ -148,19 +676,75 @@ func (di *DirectoryInput) listenForChanges() {
 	}
 }
 
-func (di *DirectoryInput) createEventsForExistingFiles() error {
-	files, err := ioutil.ReadDir(di.path)
+// isDataLink returns true if the file is the '..data' symlink that kubelet swaps to update a mounted ConfigMap or Secret
+func isDataLink(name string) bool {
+	return filepath.Base(name) == dataLink
+}
+
+// reloadAfterSwap reloads all the input files at once after the '..data' symlink of a mounted ConfigMap or Secret is
+// swapped, since the visible files are symlinks through it that produce no events of their own
+func (di *DirectoryInput) reloadAfterSwap(name string) error {
+	logger := di.logger.With().Str("method", "reloadAfterSwap").Str("link", name).Logger()
+
+	if _, err := os.Stat(name); err != nil {
+		logger.Trace().Err(err).Msg("Symlink is not in place")
+		return nil
+	}
+
+	di.mutex.RLock()
+	root := di.path
+	revision := di.revision
+	di.mutex.RUnlock()
+
+	logger.Debug().Msg("Reloading input files after symlink swap")
+	return di.reload(root, revision)
+}
+
+// includes returns true if the file should be loaded according to the include and exclude patterns
+func (di *DirectoryInput) includes(name string) bool {
+	file, err := di.relativePath(name)
 	if err != nil {
-		return err
+		return false
 	}
+	return di.filter.includesFile(file)
+}
 
-	for _, file := range files {
-		name := path.Join(di.path, file.Name())
-		di.watcher.Events <- fsnotify.Event{
-			Name: name,
-			Op:   fsnotify.Create,
-		}
+// excludes returns true if the file or directory matches any of the exclude patterns
+func (di *DirectoryInput) excludes(name string) bool {
+	file, err := di.relativePath(name)
+	if err != nil {
+		return false
 	}
+	return di.filter.excludes(file)
+}
 
-	return nil
+// watchDirectory watches the directory and all its subdirectories that are not excluded, and queues all the files
+// within them for loading
+func (di *DirectoryInput) watchDirectory(name string) error {
+	return filepath.WalkDir(name, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := di.relativePath(current)
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if !di.filter.includesDirectory(relative) {
+				return filepath.SkipDir
+			}
+			di.logger.Trace().Str("directory", relative).Msg("Watching directory")
+			return di.watcher.Add(current)
+		}
+
+		di.mutex.Lock()
+		if di.unsynced != nil {
+			di.unsynced[current] = struct{}{}
+		}
+		di.mutex.Unlock()
+		di.queue.Add(current)
+		return nil
+	})
 }
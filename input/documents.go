@@ -0,0 +1,51 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// parseDocuments parses all the '---' separated YAML documents, or JSON objects, in the file contents. Empty documents
+// are skipped, and List kinds are replaced by their items.
+func parseDocuments(contents []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(contents), 4096)
+
+	objects := make([]*unstructured.Unstructured, 0, 1)
+	for document := 0; ; document++ {
+		object := &unstructured.Unstructured{}
+		if err := decoder.Decode(&object.Object); err == io.EOF {
+			return objects, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", document, err)
+		}
+		if len(object.Object) == 0 {
+			continue
+		}
+
+		if !isList(object) {
+			objects = append(objects, object)
+			continue
+		}
+
+		list, err := object.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", document, err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			if item.GetKind() == "" || item.GetAPIVersion() == "" {
+				return nil, fmt.Errorf("document %d item %d: %w: apiVersion and kind are required", document, i, InvalidIngredient)
+			}
+			objects = append(objects, item)
+		}
+	}
+}
+
+func isList(object *unstructured.Unstructured) bool {
+	return strings.HasSuffix(object.GetKind(), "List") && object.IsList()
+}
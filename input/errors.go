@@ -3,5 +3,12 @@ package input
 import "errors"
 
 var (
-	ResourceNotFound = errors.New("resource not found")
+	ResourceNotFound  = errors.New("resource not found")
+	PatchWithoutBase  = errors.New("patch has no base resource to be applied to")
+	InvalidIngredient = errors.New("invalid ingredient")
+	IdentityChanged   = errors.New("ingredients changed the identity of the resource")
+	PolicyConflict    = errors.New("policy conflicts with existing value")
+	InvalidArchive    = errors.New("invalid archive")
+	InvalidSource     = errors.New("invalid input source")
+	UnknownSourceKind = errors.New("unknown input source kind")
 )
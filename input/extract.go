@@ -0,0 +1,161 @@
+package input
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extractTar writes the directories, files and symlinks in the tar stream to the destination directory. Symlinks must
+// resolve to a path within the destination, and no entry is written through a symlink.
+func extractTar(reader io.Reader, destination string) error {
+	archive := tar.NewReader(reader)
+	links := make([]string, 0)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return verifySymlinks(destination, links)
+		}
+		if err != nil {
+			return err
+		}
+
+		name, err := extractedPath(destination, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(name, 0755)
+		case tar.TypeReg:
+			err = writeExtractedFile(name, archive, os.FileMode(header.Mode).Perm())
+		case tar.TypeSymlink:
+			err = writeExtractedSymlink(destination, name, header.Linkname)
+			links = append(links, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip writes the directories and files in the zip file to the destination directory
+func extractZip(file, destination string) error {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, entry := range archive.File {
+		name, err := extractedPath(destination, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		contents, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtractedFile(name, contents, entry.Mode().Perm())
+		contents.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractedPath returns the path to extract the archive entry to, and fails if it would be outside the destination or
+// written through a symlink that is already extracted
+func extractedPath(destination, entry string) (string, error) {
+	name := filepath.Join(destination, filepath.FromSlash(entry))
+	if !isWithin(destination, name) {
+		return "", fmt.Errorf("%w: %s is outside the archive", InvalidArchive, entry)
+	}
+
+	current := destination
+	relative, _ := filepath.Rel(destination, name)
+	for _, segment := range strings.Split(relative, string(filepath.Separator)) {
+		if segment == "." {
+			continue
+		}
+		current = filepath.Join(current, segment)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s is written through a symlink", InvalidArchive, entry)
+		}
+	}
+	return name, nil
+}
+
+// isWithin returns true if the cleaned path is the directory or within it
+func isWithin(directory, name string) bool {
+	return name == directory || strings.HasPrefix(name, directory+string(filepath.Separator))
+}
+
+// writeExtractedSymlink creates the symlink, and fails if the target is absolute or outside the destination
+func writeExtractedSymlink(destination, name, target string) error {
+	if filepath.IsAbs(target) || !isWithin(destination, filepath.Join(filepath.Dir(name), target)) {
+		return fmt.Errorf("%w: symlink %s points outside the archive", InvalidArchive, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(target, name)
+}
+
+// verifySymlinks fails if any of the extracted symlinks resolve to a path outside the destination through other
+// symlinks. Symlinks that do not resolve are left as is.
+func verifySymlinks(destination string, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	root, err := filepath.EvalSymlinks(destination)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			continue
+		}
+		if !isWithin(root, resolved) {
+			return fmt.Errorf("%w: symlink %s resolves outside the archive", InvalidArchive, link)
+		}
+	}
+	return nil
+}
+
+func writeExtractedFile(name string, contents io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, contents)
+	return err
+}
@@ -0,0 +1,136 @@
+package input
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	contents string
+}
+
+func tarOf(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+			Mode:     0644,
+			Size:     int64(len(entry.contents)),
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(entry.contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buffer
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		invalid bool
+	}{
+		{
+			name: "files and directories",
+			entries: []tarEntry{
+				{name: "manifests/", typeflag: tar.TypeDir},
+				{name: "manifests/a.yaml", typeflag: tar.TypeReg, contents: "a"},
+			},
+		},
+		{
+			name: "symlink within the archive",
+			entries: []tarEntry{
+				{name: "manifests/a.yaml", typeflag: tar.TypeReg, contents: "a"},
+				{name: "b.yaml", typeflag: tar.TypeSymlink, linkname: "manifests/a.yaml"},
+			},
+		},
+		{
+			name: "path outside the archive",
+			entries: []tarEntry{
+				{name: "../a.yaml", typeflag: tar.TypeReg, contents: "a"},
+			},
+			invalid: true,
+		},
+		{
+			name: "absolute symlink",
+			entries: []tarEntry{
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "/tmp"},
+			},
+			invalid: true,
+		},
+		{
+			name: "relative symlink outside the archive",
+			entries: []tarEntry{
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "../outside"},
+			},
+			invalid: true,
+		},
+		{
+			name: "symlink resolving outside the archive through another symlink",
+			entries: []tarEntry{
+				{name: "dot", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "dot/.."},
+			},
+			invalid: true,
+		},
+		{
+			name: "file written through a symlink",
+			entries: []tarEntry{
+				{name: "manifests/", typeflag: tar.TypeDir},
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "manifests"},
+				{name: "link/a.yaml", typeflag: tar.TypeReg, contents: "a"},
+			},
+			invalid: true,
+		},
+		{
+			name: "file replacing a symlink",
+			entries: []tarEntry{
+				{name: "a.yaml", typeflag: tar.TypeReg, contents: "a"},
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "a.yaml"},
+				{name: "link", typeflag: tar.TypeReg, contents: "b"},
+			},
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			destination := filepath.Join(root, "checkout")
+
+			err := extractTar(tarOf(t, test.entries), destination)
+			if test.invalid && !errors.Is(err, InvalidArchive) {
+				t.Fatalf("expected %v, got %v", InvalidArchive, err)
+			}
+			if !test.invalid && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) > 1 {
+				t.Fatalf("expected only the checkout in %s, got %d entries", root, len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package input
+
+import (
+	"path"
+	"strings"
+
+	"github.com/knadh/koanf"
+)
+
+// fileFilter decides which files in the input directory are loaded, from the glob patterns in 'input.include' and
+// 'input.exclude'. Patterns without a slash match the name of the file or directory, and other patterns match the
+// path relative to the input directory where '**' matches any number of directories. Files are loaded if they match
+// any include pattern, or there are none, and no exclude pattern. Excluded directories are not watched. The internal
+// files and directories of mounted ConfigMaps and Secrets, with names starting with '..', are always excluded.
+type fileFilter struct {
+	include []string
+	exclude []string
+}
+
+func newFileFilter(config *koanf.Koanf) (*fileFilter, error) {
+	filter := &fileFilter{
+		include: config.Strings("input.include"),
+		exclude: config.Strings("input.exclude"),
+	}
+	for _, pattern := range append(filter.include, filter.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
+
+// includesFile returns true if the file at the relative path should be loaded
+func (ff *fileFilter) includesFile(file string) bool {
+	if ff.excludes(file) {
+		return false
+	}
+	if len(ff.include) == 0 {
+		return true
+	}
+	for _, pattern := range ff.include {
+		if matchesGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// includesDirectory returns true if the directory at the relative path should be watched
+func (ff *fileFilter) includesDirectory(directory string) bool {
+	return directory == "." || !ff.excludes(directory)
+}
+
+func (ff *fileFilter) excludes(file string) bool {
+	if isAtomicWriterInternal(file) {
+		return true
+	}
+	for _, pattern := range ff.exclude {
+		if matchesGlob(pattern, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAtomicWriterInternal returns true if the relative path is within the timestamped directories or '..data' symlink
+// that kubelet uses to atomically update the files of mounted ConfigMaps and Secrets
+func isAtomicWriterInternal(file string) bool {
+	for _, segment := range strings.Split(file, "/") {
+		if segment != ".." && strings.HasPrefix(segment, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesGlob(pattern, file string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(file))
+		return matched
+	}
+	return matchesSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchesSegments(pattern, file []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(file); skip++ {
+				if matchesSegments(pattern[1:], file[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(file) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], file[0]); !matched {
+			return false
+		}
+		pattern, file = pattern[1:], file[1:]
+	}
+	return len(file) == 0
+}
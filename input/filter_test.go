@@ -0,0 +1,103 @@
+package input
+
+import "testing"
+
+func TestMatchesGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		matches bool
+	}{
+		{pattern: "*.yaml", file: "a.yaml", matches: true},
+		{pattern: "*.yaml", file: "apps/web/a.yaml", matches: true},
+		{pattern: "*.yaml", file: "a.yml", matches: false},
+		{pattern: ".*", file: "apps/.hidden", matches: true},
+		{pattern: ".*", file: ".git", matches: true},
+		{pattern: "apps", file: "apps", matches: true},
+		{pattern: "apps", file: "other/apps", matches: true},
+		{pattern: "apps/*.yaml", file: "apps/a.yaml", matches: true},
+		{pattern: "apps/*.yaml", file: "apps/web/a.yaml", matches: false},
+		{pattern: "apps/*.yaml", file: "other/apps/a.yaml", matches: false},
+		{pattern: "apps/**/*.yaml", file: "apps/a.yaml", matches: true},
+		{pattern: "apps/**/*.yaml", file: "apps/web/a.yaml", matches: true},
+		{pattern: "apps/**/*.yaml", file: "apps/web/prod/a.yaml", matches: true},
+		{pattern: "apps/**/*.yaml", file: "other/a.yaml", matches: false},
+		{pattern: "**/prod/*.yaml", file: "prod/a.yaml", matches: true},
+		{pattern: "**/prod/*.yaml", file: "apps/web/prod/a.yaml", matches: true},
+		{pattern: "**/prod/*.yaml", file: "apps/prod/web/a.yaml", matches: false},
+		{pattern: "apps/**", file: "apps/web/a.yaml", matches: true},
+		{pattern: "apps/**", file: "apps", matches: true},
+		{pattern: "apps/**", file: "other/a.yaml", matches: false},
+		{pattern: "apps/web", file: "apps/web/a.yaml", matches: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.file, func(t *testing.T) {
+			if matches := matchesGlob(test.pattern, test.file); matches != test.matches {
+				t.Errorf("matchesGlob(%q, %q) = %t, expected %t", test.pattern, test.file, matches, test.matches)
+			}
+		})
+	}
+}
+
+func TestFileFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		file      string
+		includes  bool
+		directory string
+		watches   bool
+	}{
+		{name: "no patterns", file: "a.yaml", includes: true, directory: "apps", watches: true},
+		{name: "included", include: []string{"*.yaml"}, file: "apps/a.yaml", includes: true, directory: "apps", watches: true},
+		{name: "not included", include: []string{"*.yaml"}, file: "apps/README.md", includes: false, directory: "apps", watches: true},
+		{name: "excluded", exclude: []string{"*.md"}, file: "README.md", includes: false, directory: "docs", watches: true},
+		{name: "exclude wins over include", include: []string{"*"}, exclude: []string{"*.md"}, file: "README.md", includes: false, directory: "apps", watches: true},
+		{name: "excluded directory", exclude: []string{"vendor"}, file: "vendor/a.yaml", includes: true, directory: "vendor", watches: false},
+		{name: "root is always watched", exclude: []string{"*"}, file: "a.yaml", includes: false, directory: ".", watches: true},
+		{name: "configmap data", file: "..data/a.yaml", includes: false, directory: "..data", watches: false},
+		{name: "configmap generation", file: "..2026_10_18_10_00_00.123/a.yaml", includes: false, directory: "..2026_10_18_10_00_00.123", watches: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := &fileFilter{include: test.include, exclude: test.exclude}
+			if includes := filter.includesFile(test.file); includes != test.includes {
+				t.Errorf("includesFile(%q) = %t, expected %t", test.file, includes, test.includes)
+			}
+			if watches := filter.includesDirectory(test.directory); watches != test.watches {
+				t.Errorf("includesDirectory(%q) = %t, expected %t", test.directory, watches, test.watches)
+			}
+		})
+	}
+}
+
+func TestIsAtomicWriterInternal(t *testing.T) {
+	tests := []struct {
+		file     string
+		internal bool
+	}{
+		{file: "..data", internal: true},
+		{file: "..data_tmp", internal: true},
+		{file: "..2026_10_18_10_00_00.123", internal: true},
+		{file: "..2026_10_18_10_00_00.123/a.yaml", internal: true},
+		{file: "config/..data", internal: true},
+		{file: "config/..data/a.yaml", internal: true},
+		{file: "a.yaml", internal: false},
+		{file: ".hidden", internal: false},
+		{file: "a..yaml", internal: false},
+		{file: "config/a.yaml", internal: false},
+		{file: "..", internal: false},
+		{file: ".", internal: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			if internal := isAtomicWriterInternal(test.file); internal != test.internal {
+				t.Errorf("isAtomicWriterInternal(%q) = %t, expected %t", test.file, internal, test.internal)
+			}
+		})
+	}
+}
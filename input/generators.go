@@ -0,0 +1,266 @@
+package input
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ConfigMapGeneratorKind is the kind of ingredients that generate a ConfigMap with a content-hash suffixed name
+	ConfigMapGeneratorKind = "ConfigMapGenerator"
+	// SecretGeneratorKind is the kind of ingredients that generate a Secret with a content-hash suffixed name
+	SecretGeneratorKind = "SecretGenerator"
+	// GeneratedNameAnnotation is set on generated ConfigMaps and Secrets, and holds the name of the generator that is
+	// used to reference them from other resources
+	GeneratedNameAnnotation = "kokk.dolittle.io/generated-name"
+)
+
+type generatorSpec struct {
+	Literals []string `json:"literals"`
+	Files    []string `json:"files"`
+	Type     string   `json:"type"`
+}
+
+// objectName identifies an object by its kind, namespace and the name it is referenced with
+type objectName struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// generatedNameOf returns the name that references the object, if it is a generated ConfigMap or Secret
+func generatedNameOf(object *unstructured.Unstructured) (objectName, bool) {
+	name, found := object.GetAnnotations()[GeneratedNameAnnotation]
+	if !found {
+		return objectName{}, false
+	}
+	return objectName{kind: object.GetKind(), namespace: object.GetNamespace(), name: name}, true
+}
+
+// expandGenerator builds the ConfigMap or Secret described by the generator ingredient loaded from the file. The data
+// is read from the literal 'KEY=value' pairs and the files listed as 'path' or 'key=path', relative to the generator
+// file. The name of the generated object is suffixed with a hash of its contents.
+func expandGenerator(file string, object *unstructured.Unstructured) ([]*unstructured.Unstructured, []string, error) {
+	spec := generatorSpec{}
+	if err := convertField(object.Object["spec"], &spec); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", InvalidIngredient, err)
+	}
+
+	data := make(map[string][]byte)
+	for _, literal := range spec.Literals {
+		key, value, found := strings.Cut(literal, "=")
+		if !found || key == "" {
+			return nil, nil, fmt.Errorf("%w: literal %q is not on the form KEY=value", InvalidIngredient, literal)
+		}
+		data[key] = []byte(value)
+	}
+
+	sources := make([]string, 0, len(spec.Files))
+	for _, source := range spec.Files {
+		key, name := generatorFile(file, source)
+		contents, err := os.ReadFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		data[key] = contents
+		sources = append(sources, name)
+	}
+
+	generated := &unstructured.Unstructured{Object: make(map[string]any)}
+	generated.SetAPIVersion("v1")
+	generated.SetNamespace(object.GetNamespace())
+	generated.SetLabels(object.GetLabels())
+
+	switch object.GetKind() {
+	case ConfigMapGeneratorKind:
+		generated.SetKind("ConfigMap")
+		text, binary := make(map[string]any), make(map[string]any)
+		for key, value := range data {
+			if utf8.Valid(value) {
+				text[key] = string(value)
+			} else {
+				binary[key] = base64.StdEncoding.EncodeToString(value)
+			}
+		}
+		generated.Object["data"] = text
+		if len(binary) > 0 {
+			generated.Object["binaryData"] = binary
+		}
+	case SecretGeneratorKind:
+		generated.SetKind("Secret")
+		encoded := make(map[string]any)
+		for key, value := range data {
+			encoded[key] = base64.StdEncoding.EncodeToString(value)
+		}
+		generated.Object["data"] = encoded
+		if spec.Type == "" {
+			spec.Type = "Opaque"
+		}
+		generated.Object["type"] = spec.Type
+	}
+
+	hash, err := contentHash(generated)
+	if err != nil {
+		return nil, nil, err
+	}
+	generated.SetName(object.GetName() + "-" + hash)
+
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[GeneratedNameAnnotation] = object.GetName()
+	generated.SetAnnotations(annotations)
+
+	return []*unstructured.Unstructured{generated}, sources, nil
+}
+
+// generatorFile returns the key and path of a file listed in a generator loaded from the file
+func generatorFile(file, source string) (string, string) {
+	key, name, found := strings.Cut(source, "=")
+	if !found {
+		key, name = filepath.Base(source), source
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(file), name)
+	}
+	return key, name
+}
+
+// generatorFiles returns the paths of the files that the generator ingredient loaded from the file reads
+func generatorFiles(file string, object *unstructured.Unstructured) []string {
+	spec := generatorSpec{}
+	if err := convertField(object.Object["spec"], &spec); err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(spec.Files))
+	for _, source := range spec.Files {
+		_, name := generatorFile(file, source)
+		names = append(names, name)
+	}
+	return names
+}
+
+// contentHash returns a short hash of the kind, type and data of the object
+func contentHash(object *unstructured.Unstructured) (string, error) {
+	encoded, err := json.Marshal(map[string]any{
+		"kind":       object.GetKind(),
+		"type":       object.Object["type"],
+		"data":       object.Object["data"],
+		"binaryData": object.Object["binaryData"],
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])[:10], nil
+}
+
+// rewriteReferences replaces references to generated ConfigMaps and Secrets in the pod templates of the object with
+// the current hashed names
+func rewriteReferences(object *unstructured.Unstructured, names map[objectName]string) {
+	if len(names) == 0 {
+		return
+	}
+
+	namespace := object.GetNamespace()
+	forEachReference(object, func(kind string, reference map[string]any, field string) {
+		name, ok := reference[field].(string)
+		if !ok {
+			return
+		}
+		if hashed, found := names[objectName{kind: kind, namespace: namespace, name: name}]; found {
+			reference[field] = hashed
+		}
+	})
+}
+
+// forEachReference calls the function with every field in the pod templates of the object that references another
+// object by name, and the kind of the referenced object
+func forEachReference(object *unstructured.Unstructured, reference func(kind string, parent map[string]any, field string)) {
+	for _, template := range podTemplatePaths {
+		spec, found, _ := unstructured.NestedMap(object.Object, append(template, "spec")...)
+		if !found {
+			continue
+		}
+
+		reference("ServiceAccount", spec, "serviceAccountName")
+
+		for _, volume := range mapsIn(spec["volumes"]) {
+			if configMap, ok := volume["configMap"].(map[string]any); ok {
+				reference("ConfigMap", configMap, "name")
+			}
+			if secret, ok := volume["secret"].(map[string]any); ok {
+				reference("Secret", secret, "secretName")
+			}
+			if claim, ok := volume["persistentVolumeClaim"].(map[string]any); ok {
+				reference("PersistentVolumeClaim", claim, "claimName")
+			}
+			if projected, ok := volume["projected"].(map[string]any); ok {
+				for _, source := range mapsIn(projected["sources"]) {
+					if configMap, ok := source["configMap"].(map[string]any); ok {
+						reference("ConfigMap", configMap, "name")
+					}
+					if secret, ok := source["secret"].(map[string]any); ok {
+						reference("Secret", secret, "name")
+					}
+				}
+			}
+		}
+
+		for _, pullSecret := range mapsIn(spec["imagePullSecrets"]) {
+			reference("Secret", pullSecret, "name")
+		}
+
+		for _, containers := range []string{"initContainers", "containers"} {
+			for _, container := range mapsIn(spec[containers]) {
+				for _, envFrom := range mapsIn(container["envFrom"]) {
+					if configMap, ok := envFrom["configMapRef"].(map[string]any); ok {
+						reference("ConfigMap", configMap, "name")
+					}
+					if secret, ok := envFrom["secretRef"].(map[string]any); ok {
+						reference("Secret", secret, "name")
+					}
+				}
+				for _, env := range mapsIn(container["env"]) {
+					valueFrom, ok := env["valueFrom"].(map[string]any)
+					if !ok {
+						continue
+					}
+					if configMap, ok := valueFrom["configMapKeyRef"].(map[string]any); ok {
+						reference("ConfigMap", configMap, "name")
+					}
+					if secret, ok := valueFrom["secretKeyRef"].(map[string]any); ok {
+						reference("Secret", secret, "name")
+					}
+				}
+			}
+		}
+
+		_ = unstructured.SetNestedMap(object.Object, spec, append(template, "spec")...)
+	}
+}
+
+func mapsIn(field any) []map[string]any {
+	list, ok := field.([]any)
+	if !ok {
+		return nil
+	}
+	maps := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if itemMap, ok := item.(map[string]any); ok {
+			maps = append(maps, itemMap)
+		}
+	}
+	return maps
+}
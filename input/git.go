@@ -0,0 +1,119 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+)
+
+// gitTimeout is the timeout of each git command
+const gitTimeout = time.Minute
+
+// GitInput loads the input files from the commit that a ref points to in a local git repository. The ref is polled
+// every 'input.git.interval', optionally fetching from the remotes first, and the files at a new commit are loaded all
+// at once from a checkout that is separate from the working directory of the repository.
+type GitInput struct {
+	*snapshotSource
+	repository string
+	ref        string
+	directory  string
+	fetch      bool
+	logger     *zerolog.Logger
+}
+
+func NewGitInput(config *koanf.Koanf, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*GitInput, error) {
+	repository := config.String("input.git.repository")
+	ref := config.String("input.git.ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	loggerWithRepository := logger.With().Str("repository", repository).Str("ref", ref).Logger()
+
+	directory := config.String("input.git.directory")
+	source, err := newSnapshotSource(config, GitSource, Revision{Source: repository, Ref: ref}, directory, converter, types, patcher, &loggerWithRepository)
+	if err != nil {
+		return nil, err
+	}
+
+	git := &GitInput{
+		snapshotSource: source,
+		repository:     repository,
+		ref:            ref,
+		directory:      filepath.Clean(directory),
+		fetch:          config.Bool("input.git.fetch"),
+		logger:         &loggerWithRepository,
+	}
+
+	if _, err := git.resolve(); err != nil {
+		return nil, err
+	}
+
+	source.start(git, config.Duration("input.git.interval"))
+
+	return git, nil
+}
+
+// latest returns the commit that the ref points to, after fetching from the remotes if enabled
+func (gi *GitInput) latest() (string, error) {
+	logger := gi.logger.With().Str("method", "latest").Logger()
+
+	if gi.fetch {
+		if _, err := gi.git("fetch", "--quiet"); err != nil {
+			logger.Error().Err(err).Msg("Could not fetch from remotes")
+			return "", err
+		}
+	}
+
+	commit, err := gi.resolve()
+	if err != nil {
+		logger.Error().Err(err).Msg("Could not resolve ref")
+		return "", err
+	}
+	return commit, nil
+}
+
+// resolve returns the commit SHA that the ref points to
+func (gi *GitInput) resolve() (string, error) {
+	output, err := gi.git("rev-parse", "--verify", "--quiet", gi.ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("ref %s: %w", gi.ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// extract writes the files of the input directory at the commit to the checkout directory
+func (gi *GitInput) extract(commit, checkout string) error {
+	args := []string{"archive", "--format=tar", commit}
+	if gi.directory != "." {
+		args = append(args, "--", gi.directory)
+	}
+	archive, err := gi.git(args...)
+	if err != nil {
+		return err
+	}
+
+	return extractTar(bytes.NewReader(archive), checkout)
+}
+
+func (gi *GitInput) git(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, "git", append([]string{"-C", gi.repository}, args...)...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
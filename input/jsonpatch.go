@@ -0,0 +1,117 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// JSONPatchKind is the kind of input files that apply RFC 6902 JSON Patch operations to the matching input resources
+const JSONPatchKind = "JSONPatch"
+
+type jsonPatch struct {
+	file       string
+	target     jsonPatchTarget
+	selector   labels.Selector
+	operations jsonpatch.Patch
+}
+
+// jsonPatchTarget selects the resources a JSON Patch is applied to. All the fields that are set must match.
+type jsonPatchTarget struct {
+	Id            string `json:"id"`
+	Group         string `json:"group"`
+	Version       string `json:"version"`
+	Kind          string `json:"kind"`
+	Name          string `json:"name"`
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"labelSelector"`
+}
+
+func parseJSONPatch(file string, object *unstructured.Unstructured) (*jsonPatch, error) {
+	patch := &jsonPatch{
+		file:     file,
+		selector: labels.Everything(),
+	}
+
+	target, found := object.Object["target"]
+	if !found {
+		return nil, fmt.Errorf("%s has no target: %w", JSONPatchKind, InvalidIngredient)
+	}
+	if err := convertField(target, &patch.target); err != nil {
+		return nil, err
+	}
+	if patch.target == (jsonPatchTarget{}) {
+		return nil, fmt.Errorf("%s target selects no resources: %w", JSONPatchKind, InvalidIngredient)
+	}
+
+	if patch.target.LabelSelector != "" {
+		selector, err := labels.Parse(patch.target.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		patch.selector = selector
+	}
+
+	data, err := json.Marshal(object.Object["patch"])
+	if err != nil {
+		return nil, err
+	}
+	operations, err := jsonpatch.DecodePatch(data)
+	if err != nil {
+		return nil, err
+	}
+	patch.operations = operations
+
+	return patch, nil
+}
+
+func (p *jsonPatch) matches(id string, object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+
+	switch {
+	case p.target.Id != "" && p.target.Id != id:
+		return false
+	case p.target.Group != "" && p.target.Group != gvk.Group:
+		return false
+	case p.target.Version != "" && p.target.Version != gvk.Version:
+		return false
+	case p.target.Kind != "" && p.target.Kind != gvk.Kind:
+		return false
+	case p.target.Name != "" && p.target.Name != object.GetName():
+		return false
+	case p.target.Namespace != "" && p.target.Namespace != object.GetNamespace():
+		return false
+	}
+
+	return p.selector.Matches(labels.Set(object.GetLabels()))
+}
+
+func (p *jsonPatch) apply(object *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	data, err := object.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	patchedData, err := p.operations.Apply(data)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %w", p.file, err)
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(patchedData); err != nil {
+		return nil, fmt.Errorf("file %s: %w", p.file, err)
+	}
+	return patched, nil
+}
+
+// convertField converts a field of an Unstructured object into a typed struct
+func convertField(field any, into any) error {
+	data, err := json.Marshal(field)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
@@ -0,0 +1,70 @@
+package input
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// KokkGroup is the API group of the Kokk-specific ingredient kinds that are processed by the input, and never applied
+// to the cluster themselves
+const KokkGroup = "kokk.dolittle.io"
+
+func isKokkKind(object *unstructured.Unstructured) bool {
+	return object.GroupVersionKind().Group == KokkGroup
+}
+
+// GeneratedFromAnnotation is set on objects expanded from a Kokk-specific ingredient kind, and holds the identity of
+// the ingredient they were generated from
+const GeneratedFromAnnotation = "kokk.dolittle.io/generated-from"
+
+// expand returns the Kubernetes objects described by the object loaded from the file, the identity of the
+// Kokk-specific ingredient they were generated from, and the other files that were read to generate them. Objects that
+// are not Kokk-specific are returned as is.
+func expand(file string, object *unstructured.Unstructured) ([]*unstructured.Unstructured, string, []string, error) {
+	if !isKokkKind(object) {
+		return []*unstructured.Unstructured{object}, "", nil, nil
+	}
+
+	parent := kokkIdFor(object)
+	var objects []*unstructured.Unstructured
+	var sources []string
+	var err error
+	switch object.GetKind() {
+	case MicroserviceKind:
+		objects, err = expandMicroservice(object)
+	case EnvironmentMatrixKind:
+		objects, err = expandEnvironmentMatrix(object)
+	case ConfigMapGeneratorKind, SecretGeneratorKind:
+		objects, sources, err = expandGenerator(file, object)
+	default:
+		err = fmt.Errorf("%w: unknown kind %s", InvalidIngredient, object.GetKind())
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, generated := range objects {
+		annotations := generated.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[GeneratedFromAnnotation] = parent
+		generated.SetAnnotations(annotations)
+	}
+	return objects, parent, sources, nil
+}
+
+// kokkIdFor returns an identity for a Kokk-specific ingredient on the same form as the resource ids
+func kokkIdFor(object *unstructured.Unstructured) string {
+	resource := strings.ToLower(object.GetKind()) + "s"
+	if strings.HasSuffix(resource, "xs") || strings.HasSuffix(resource, "ss") {
+		resource = strings.TrimSuffix(resource, "s") + "es"
+	}
+	if object.GetNamespace() == "" {
+		return path.Join(object.GetAPIVersion(), resource, object.GetName())
+	}
+	return path.Join(object.GetAPIVersion(), "namespaces", object.GetNamespace(), resource, object.GetName())
+}
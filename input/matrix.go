@@ -0,0 +1,121 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// EnvironmentMatrixKind is the kind of ingredients that expand a template object into one object per environment
+	EnvironmentMatrixKind = "EnvironmentMatrix"
+	// EnvironmentAnnotation is set on the objects expanded from an EnvironmentMatrix, and holds the environment name
+	EnvironmentAnnotation = "kokk.dolittle.io/environment"
+)
+
+type environmentMatrixSpec struct {
+	Template     map[string]any `json:"template"`
+	Environments []environment  `json:"environments"`
+}
+
+// environment describes the overrides applied to the template for one environment. The name of the expanded object
+// defaults to the environment name followed by a dash and the template name.
+type environment struct {
+	Name        string            `json:"name"`
+	ObjectName  string            `json:"objectName"`
+	Namespace   string            `json:"namespace"`
+	Replicas    *int64            `json:"replicas"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Overrides   map[string]any    `json:"overrides"`
+}
+
+// expandEnvironmentMatrix expands an EnvironmentMatrix ingredient into a copy of the template for each environment.
+// Occurrences of '${environment}' in string values of the template are replaced with the environment name.
+func expandEnvironmentMatrix(object *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	spec := environmentMatrixSpec{}
+	if err := convertField(object.Object["spec"], &spec); err != nil {
+		return nil, fmt.Errorf("%w: %s", InvalidIngredient, err)
+	}
+	if len(spec.Template) == 0 {
+		return nil, fmt.Errorf("%w: template is required", InvalidIngredient)
+	}
+
+	seen := make(map[string]bool)
+	objects := make([]*unstructured.Unstructured, 0, len(spec.Environments))
+	for _, environment := range spec.Environments {
+		if environment.Name == "" {
+			return nil, fmt.Errorf("%w: environments require a name", InvalidIngredient)
+		}
+		if seen[environment.Name] {
+			return nil, fmt.Errorf("%w: environment %s is declared more than once", InvalidIngredient, environment.Name)
+		}
+		seen[environment.Name] = true
+
+		expanded := &unstructured.Unstructured{
+			Object: substitute(runtime.DeepCopyJSON(spec.Template), environment.Name).(map[string]any),
+		}
+		if err := environment.apply(expanded); err != nil {
+			return nil, err
+		}
+		objects = append(objects, expanded)
+	}
+	return objects, nil
+}
+
+// apply sets the name, namespace, replicas, labels and annotations of the environment on the expanded template, and
+// merges in the overrides
+func (e environment) apply(object *unstructured.Unstructured) error {
+	if e.Overrides != nil {
+		deepMerge(object.Object, runtime.DeepCopyJSON(e.Overrides))
+	}
+
+	name := e.ObjectName
+	if name == "" {
+		if object.GetName() == "" {
+			return fmt.Errorf("%w: the template or environment %s requires a name", InvalidIngredient, e.Name)
+		}
+		name = e.Name + "-" + object.GetName()
+	}
+	object.SetName(name)
+
+	if e.Namespace != "" {
+		object.SetNamespace(e.Namespace)
+	}
+
+	if e.Replicas != nil {
+		if err := unstructured.SetNestedField(object.Object, *e.Replicas, "spec", "replicas"); err != nil {
+			return err
+		}
+	}
+
+	if err := applyLabels(object, e.Labels); err != nil {
+		return err
+	}
+
+	annotations := make(map[string]string, len(e.Annotations)+1)
+	for key, value := range e.Annotations {
+		annotations[key] = value
+	}
+	annotations[EnvironmentAnnotation] = e.Name
+	return mergeStringMap(object.Object, annotations, "metadata", "annotations")
+}
+
+// substitute replaces '${environment}' in all string values in the field with the environment name
+func substitute(field any, environment string) any {
+	switch value := field.(type) {
+	case string:
+		return strings.ReplaceAll(value, "${environment}", environment)
+	case map[string]any:
+		for key, item := range value {
+			value[key] = substitute(item, environment)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = substitute(item, environment)
+		}
+	}
+	return field
+}
@@ -0,0 +1,148 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// PriorityAnnotation is the annotation that sets the priority of an ingredient when several files describe the same
+	// resource. Ingredients with a higher priority are merged on top of the ones with a lower priority.
+	PriorityAnnotation = "kokk.dolittle.io/priority"
+	// PatchAnnotation is the annotation that marks an ingredient as an overlay, that is applied as a strategic merge
+	// patch on top of the merged base ingredients
+	PatchAnnotation = "kokk.dolittle.io/patch"
+)
+
+type Patcher interface {
+	StrategicMergePatch(original, patch *unstructured.Unstructured) (*unstructured.Unstructured, error)
+}
+
+// ingredient is an object loaded from an input file that describes the resource with the id. Objects generated from
+// Kokk-specific ingredient kinds record the identity of the generating ingredient as their parent, and objects renamed
+// by the name transformers record the name they were referenced by before.
+type ingredient struct {
+	file        string
+	id          string
+	object      *unstructured.Unstructured
+	parent      string
+	renamedFrom string
+}
+
+// merge deep-merges the base ingredients in order of ascending priority, and then by filename. Maps are merged
+// recursively, while lists and other values from later ingredients replace the earlier ones. The overlay ingredients
+// marked with the PatchAnnotation are then applied in the same order as strategic merge patches. It returns the merged
+// object, the files that contributed to it in the order they were merged, and the parent of the first ingredient that
+// was generated from a Kokk-specific ingredient kind.
+func merge(ingredients []ingredient, patcher Patcher) (*unstructured.Unstructured, []string, string, error) {
+	priorities := make([]int, len(ingredients))
+	for i, ingredient := range ingredients {
+		priority, err := priorityOf(ingredient.object)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("file %s: %w", ingredient.file, err)
+		}
+		priorities[i] = priority
+	}
+
+	order := make([]int, len(ingredients))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if priorities[order[i]] != priorities[order[j]] {
+			return priorities[order[i]] < priorities[order[j]]
+		}
+		return ingredients[order[i]].file < ingredients[order[j]].file
+	})
+
+	sorted := make([]ingredient, len(ingredients))
+	for i, index := range order {
+		sorted[i] = ingredients[index]
+	}
+	ingredients = sorted
+
+	parent := ""
+	for _, ingredient := range ingredients {
+		if ingredient.parent != "" {
+			parent = ingredient.parent
+			break
+		}
+	}
+
+	merged := &unstructured.Unstructured{Object: make(map[string]any)}
+	files := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		if isPatch(ingredient.object) {
+			continue
+		}
+		deepMerge(merged.Object, ingredient.object.DeepCopy().Object)
+		files = append(files, ingredient.file)
+	}
+
+	for _, ingredient := range ingredients {
+		if !isPatch(ingredient.object) {
+			continue
+		}
+		if len(files) == 0 {
+			return nil, nil, "", fmt.Errorf("file %s: %w", ingredient.file, PatchWithoutBase)
+		}
+
+		patched, err := patcher.StrategicMergePatch(merged, ingredient.object)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("file %s: %w", ingredient.file, err)
+		}
+		merged = patched
+		files = append(files, ingredient.file)
+	}
+
+	removeAnnotation(merged, PriorityAnnotation)
+	removeAnnotation(merged, PatchAnnotation)
+	return merged, files, parent, nil
+}
+
+func isPatch(object *unstructured.Unstructured) bool {
+	return object.GetAnnotations()[PatchAnnotation] == "true"
+}
+
+func priorityOf(object *unstructured.Unstructured) (int, error) {
+	value, found := object.GetAnnotations()[PriorityAnnotation]
+	if !found {
+		return 0, nil
+	}
+
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", PriorityAnnotation, err)
+	}
+	return priority, nil
+}
+
+// deepMerge merges the source map into the destination map
+func deepMerge(destination, source map[string]any) {
+	for key, value := range source {
+		sourceMap, sourceIsMap := value.(map[string]any)
+		destinationMap, destinationIsMap := destination[key].(map[string]any)
+		if sourceIsMap && destinationIsMap {
+			deepMerge(destinationMap, sourceMap)
+			continue
+		}
+		destination[key] = value
+	}
+}
+
+func removeAnnotation(object *unstructured.Unstructured, annotation string) {
+	annotations := object.GetAnnotations()
+	if _, found := annotations[annotation]; !found {
+		return
+	}
+
+	delete(annotations, annotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(object.Object, "metadata", "annotations")
+		return
+	}
+	object.SetAnnotations(annotations)
+}
@@ -0,0 +1,196 @@
+package input
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MicroserviceKind is the kind of ingredients that describe a Dolittle microservice, which are expanded into a
+// Deployment, a Service and a ConfigMap holding its environment variables
+const MicroserviceKind = "Microservice"
+
+type microserviceSpec struct {
+	Id          string             `json:"id"`
+	Name        string             `json:"name"`
+	Kind        string             `json:"kind"`
+	Application microserviceOwner  `json:"application"`
+	Environment string             `json:"environment"`
+	Tenant      microserviceOwner  `json:"tenant"`
+	Image       string             `json:"image"`
+	Replicas    *int64             `json:"replicas"`
+	Ports       []microservicePort `json:"ports"`
+	Config      map[string]string  `json:"config"`
+}
+
+type microserviceOwner struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type microservicePort struct {
+	Name          string `json:"name"`
+	ContainerPort int64  `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// expandMicroservice expands a Microservice ingredient into the Kubernetes objects that run it
+func expandMicroservice(object *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	spec := microserviceSpec{}
+	if err := convertField(object.Object["spec"], &spec); err != nil {
+		return nil, fmt.Errorf("%w: %s", InvalidIngredient, err)
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	if spec.Name == "" {
+		spec.Name = object.GetName()
+	}
+	if spec.Kind == "" {
+		spec.Kind = "simple"
+	}
+	if spec.Replicas == nil {
+		replicas := int64(1)
+		spec.Replicas = &replicas
+	}
+
+	name := object.GetName()
+	namespace := object.GetNamespace()
+	labels := spec.labels()
+	annotations := spec.annotations()
+	configName := name + "-env-variables"
+
+	config := make(map[string]any, len(spec.Config))
+	for key, value := range spec.Config {
+		config[key] = value
+	}
+	configMap := newObject("v1", "ConfigMap", configName, namespace, labels, annotations)
+	configMap.Object["data"] = config
+
+	containerPorts := make([]any, 0, len(spec.Ports))
+	servicePorts := make([]any, 0, len(spec.Ports))
+	for _, port := range spec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		containerPorts = append(containerPorts, map[string]any{
+			"name":          port.Name,
+			"containerPort": port.ContainerPort,
+			"protocol":      protocol,
+		})
+		servicePorts = append(servicePorts, map[string]any{
+			"name":       port.Name,
+			"port":       port.ContainerPort,
+			"targetPort": port.Name,
+			"protocol":   protocol,
+		})
+	}
+
+	deployment := newObject("apps/v1", "Deployment", name, namespace, labels, annotations)
+	deployment.Object["spec"] = map[string]any{
+		"replicas": *spec.Replicas,
+		"selector": map[string]any{
+			"matchLabels": toAnyMap(labels),
+		},
+		"template": map[string]any{
+			"metadata": map[string]any{
+				"labels":      toAnyMap(labels),
+				"annotations": toAnyMap(annotations),
+			},
+			"spec": map[string]any{
+				"containers": []any{
+					map[string]any{
+						"name":  "head",
+						"image": spec.Image,
+						"ports": containerPorts,
+						"envFrom": []any{
+							map[string]any{
+								"configMapRef": map[string]any{"name": configName},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	objects := []*unstructured.Unstructured{deployment, configMap}
+
+	if len(servicePorts) > 0 {
+		service := newObject("v1", "Service", name, namespace, labels, annotations)
+		service.Object["spec"] = map[string]any{
+			"selector": toAnyMap(labels),
+			"ports":    servicePorts,
+		}
+		objects = append(objects, service)
+	}
+
+	return objects, nil
+}
+
+func (spec microserviceSpec) validate() error {
+	switch {
+	case spec.Id == "":
+		return fmt.Errorf("%w: microservice id is required", InvalidIngredient)
+	case spec.Application.Id == "":
+		return fmt.Errorf("%w: application id is required", InvalidIngredient)
+	case spec.Tenant.Id == "":
+		return fmt.Errorf("%w: tenant id is required", InvalidIngredient)
+	case spec.Environment == "":
+		return fmt.Errorf("%w: environment is required", InvalidIngredient)
+	case spec.Image == "":
+		return fmt.Errorf("%w: image is required", InvalidIngredient)
+	}
+	for _, port := range spec.Ports {
+		if port.Name == "" || port.ContainerPort == 0 {
+			return fmt.Errorf("%w: ports require a name and containerPort", InvalidIngredient)
+		}
+	}
+	return nil
+}
+
+func (spec microserviceSpec) labels() map[string]string {
+	application := spec.Application.Name
+	if application == "" {
+		application = spec.Application.Id
+	}
+	tenant := spec.Tenant.Name
+	if tenant == "" {
+		tenant = spec.Tenant.Id
+	}
+	return map[string]string{
+		"application":  application,
+		"environment":  spec.Environment,
+		"microservice": spec.Name,
+		"tenant":       tenant,
+	}
+}
+
+func (spec microserviceSpec) annotations() map[string]string {
+	return map[string]string{
+		"dolittle.io/application-id":    spec.Application.Id,
+		"dolittle.io/microservice-id":   spec.Id,
+		"dolittle.io/microservice-kind": spec.Kind,
+		"dolittle.io/tenant-id":         spec.Tenant.Id,
+	}
+}
+
+func newObject(apiVersion, kind, name, namespace string, labels, annotations map[string]string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: make(map[string]any)}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	object.SetName(name)
+	object.SetNamespace(namespace)
+	object.SetLabels(labels)
+	object.SetAnnotations(annotations)
+	return object
+}
+
+func toAnyMap(values map[string]string) map[string]any {
+	result := make(map[string]any, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,212 @@
+package input
+
+import (
+	"strings"
+
+	"github.com/knadh/koanf"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type TypeProvider interface {
+	IsNamespaced(gvk schema.GroupVersionKind) (bool, error)
+}
+
+// nameTransformer adds a prefix and suffix to the names of the input objects it applies to, and overrides the namespace
+// of the namespaced ones. References to renamed objects from files within the directory of the transformer are updated
+// when the resources are built, see renamesFor and renameReferences. Selectors match labels rather than names, so
+// renaming leaves them as is, and copies of the same ingredients with different prefixes in one namespace would select
+// each other's pods. The labels of the transformer are added to the objects, their selectors and pod templates to keep
+// the copies apart. A transformer without a directory applies to all objects.
+type nameTransformer struct {
+	Directory  string            `koanf:"directory"`
+	NamePrefix string            `koanf:"namePrefix"`
+	NameSuffix string            `koanf:"nameSuffix"`
+	Namespace  string            `koanf:"namespace"`
+	Labels     map[string]string `koanf:"labels"`
+}
+
+type nameTransformers struct {
+	transformers []nameTransformer
+	types        TypeProvider
+}
+
+// newNameTransformers creates the name and namespace transformers configured in 'input.transformers'
+func newNameTransformers(config *koanf.Koanf, types TypeProvider) (*nameTransformers, error) {
+	transformers := &nameTransformers{types: types}
+	if err := config.Unmarshal("input.transformers", &transformers.transformers); err != nil {
+		return nil, err
+	}
+	return transformers, nil
+}
+
+func (nt *nameTransformers) transform(file string, object *unstructured.Unstructured) error {
+	for _, transformer := range nt.transformers {
+		if !inDirectory(file, transformer.Directory) {
+			continue
+		}
+		if transformer.Namespace != "" {
+			namespaced, err := nt.types.IsNamespaced(object.GroupVersionKind())
+			if err != nil {
+				return err
+			}
+			transformer.overrideNamespace(object, namespaced)
+		}
+		if transformer.renames() {
+			transformer.rename(object)
+		}
+		if err := applyLabels(object, transformer.Labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overrideNamespace sets the namespace of the object if it is namespaced, and of the ServiceAccounts in the same
+// namespace that are bound by RoleBindings and ClusterRoleBindings
+func (t *nameTransformer) overrideNamespace(object *unstructured.Unstructured, namespaced bool) {
+	original := object.GetNamespace()
+	if namespaced {
+		object.SetNamespace(t.Namespace)
+	}
+
+	if !isRoleBinding(object) {
+		return
+	}
+	subjects := mapsIn(object.Object["subjects"])
+	for _, subject := range subjects {
+		if subject["kind"] != "ServiceAccount" {
+			continue
+		}
+		if namespace, _ := subject["namespace"].(string); namespace == "" || namespace == original {
+			subject["namespace"] = t.Namespace
+		}
+	}
+}
+
+// rename adds the prefix and suffix to the name of the object. Generated ConfigMaps and Secrets keep their content hash
+// last.
+func (t *nameTransformer) rename(object *unstructured.Unstructured) {
+	if object.GetKind() == "Namespace" {
+		return
+	}
+
+	if base, ok := generatedNameOf(object); ok {
+		hash := strings.TrimPrefix(object.GetName(), base.name+"-")
+		annotations := object.GetAnnotations()
+		annotations[GeneratedNameAnnotation] = t.nameFor(base.name)
+		object.SetAnnotations(annotations)
+		object.SetName(t.nameFor(base.name) + "-" + hash)
+		return
+	}
+	object.SetName(t.nameFor(object.GetName()))
+}
+
+func (t *nameTransformer) nameFor(name string) string {
+	return t.NamePrefix + name + t.NameSuffix
+}
+
+// renames returns true if the transformer changes the names of the objects it applies to
+func (t *nameTransformer) renames() bool {
+	return t.NamePrefix != "" || t.NameSuffix != ""
+}
+
+// renamedObject is the name given by the name transformers to an object loaded from the file, relative to the input
+// directory
+type renamedObject struct {
+	file string
+	name string
+}
+
+// renamesFor returns the names to replace the references in the file with. An object is only referenced by its new
+// name from files that all the transformers that renamed it also apply to, so that copies of the same ingredients
+// renamed by the transformers of different directories reference their own objects. When more than one object matches
+// a reference, the one renamed by the most transformers is used.
+func (nt *nameTransformers) renamesFor(file string, renamed map[objectName][]renamedObject) map[objectName]string {
+	renames := make(map[objectName]string)
+	for name, objects := range renamed {
+		closest := -1
+		for _, object := range objects {
+			if count, shared := nt.sharedRenames(object.file, file); shared && count > closest {
+				closest = count
+				renames[name] = object.name
+			}
+		}
+	}
+	return renames
+}
+
+// sharedRenames returns the number of transformers that rename the objects in the renamed file, and true if all of
+// them also apply to the file
+func (nt *nameTransformers) sharedRenames(renamed, file string) (int, bool) {
+	count := 0
+	for _, transformer := range nt.transformers {
+		if !transformer.renames() || !inDirectory(renamed, transformer.Directory) {
+			continue
+		}
+		if !inDirectory(file, transformer.Directory) {
+			return 0, false
+		}
+		count++
+	}
+	return count, true
+}
+
+// referencedName returns the name that other objects reference the object by
+func referencedName(object *unstructured.Unstructured) string {
+	if generated, ok := generatedNameOf(object); ok {
+		return generated.name
+	}
+	return object.GetName()
+}
+
+// renameReferences replaces the names that the object references other objects by with the names given by the name
+// transformers. Only references to an object of the referenced kind, namespace and original name that is loaded from
+// the input are replaced, so that references to objects managed elsewhere, like the default ServiceAccount or the
+// built-in ClusterRoles, are left as is.
+func renameReferences(object *unstructured.Unstructured, renames map[objectName]string) {
+	if len(renames) == 0 {
+		return
+	}
+
+	rename := func(kind, namespace string, reference map[string]any, field string) {
+		name, ok := reference[field].(string)
+		if !ok {
+			return
+		}
+		if renamed, found := renames[objectName{kind: kind, namespace: namespace, name: name}]; found {
+			reference[field] = renamed
+		}
+	}
+
+	namespace := object.GetNamespace()
+	forEachReference(object, func(kind string, reference map[string]any, field string) {
+		rename(kind, namespace, reference, field)
+	})
+
+	if !isRoleBinding(object) {
+		return
+	}
+	if roleRef, ok := object.Object["roleRef"].(map[string]any); ok {
+		kind, _ := roleRef["kind"].(string)
+		roleNamespace := namespace
+		if kind == "ClusterRole" {
+			roleNamespace = ""
+		}
+		rename(kind, roleNamespace, roleRef, "name")
+	}
+	for _, subject := range mapsIn(object.Object["subjects"]) {
+		if subject["kind"] == "ServiceAccount" {
+			subjectNamespace, _ := subject["namespace"].(string)
+			if subjectNamespace == "" {
+				subjectNamespace = namespace
+			}
+			rename("ServiceAccount", subjectNamespace, subject, "name")
+		}
+	}
+}
+
+func isRoleBinding(object *unstructured.Unstructured) bool {
+	gvk := object.GroupVersionKind()
+	return gvk.Group == "rbac.authorization.k8s.io" && (gvk.Kind == "RoleBinding" || gvk.Kind == "ClusterRoleBinding")
+}
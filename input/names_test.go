@@ -0,0 +1,63 @@
+package input
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRenameReferences(t *testing.T) {
+	transformers := &nameTransformers{
+		transformers: []nameTransformer{
+			{Directory: "dev", NamePrefix: "dev-"},
+			{Directory: "test", NamePrefix: "test-"},
+			{Directory: "test/web", NameSuffix: "-web"},
+		},
+	}
+	config := objectName{kind: "ConfigMap", namespace: "apps", name: "config"}
+	renamed := map[objectName][]renamedObject{
+		config: {
+			{file: "dev/config.yaml", name: "dev-config"},
+			{file: "test/config.yaml", name: "test-config"},
+			{file: "test/web/config.yaml", name: "test-config-web"},
+		},
+	}
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{file: "dev/web.yaml", expected: "dev-config"},
+		{file: "dev/web/deployment.yaml", expected: "dev-config"},
+		{file: "test/web.yaml", expected: "test-config"},
+		{file: "test/web/deployment.yaml", expected: "test-config-web"},
+		{file: "base/web.yaml", expected: "config"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			object := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]any{"name": "web", "namespace": "apps"},
+				"spec": map[string]any{
+					"template": map[string]any{
+						"spec": map[string]any{
+							"volumes": []any{
+								map[string]any{"name": "config", "configMap": map[string]any{"name": "config"}},
+							},
+						},
+					},
+				},
+			}}
+
+			renameReferences(object, transformers.renamesFor(test.file, renamed))
+
+			volumes, _, _ := unstructured.NestedSlice(object.Object, "spec", "template", "spec", "volumes")
+			name := volumes[0].(map[string]any)["configMap"].(map[string]any)["name"]
+			if name != test.expected {
+				t.Errorf("reference from %s was renamed to %v, expected %s", test.file, name, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knadh/koanf"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// labelPolicy injects common labels and annotations into the input objects it applies to. A policy without a
+// directory or namespace applies to all objects.
+type labelPolicy struct {
+	Directory    string            `koanf:"directory"`
+	Namespace    string            `koanf:"namespace"`
+	Labels       map[string]string `koanf:"labels"`
+	Annotations  map[string]string `koanf:"annotations"`
+	PodTemplates bool              `koanf:"podTemplates"`
+	Selectors    bool              `koanf:"selectors"`
+	Overwrite    bool              `koanf:"overwrite"`
+}
+
+type labelPolicies struct {
+	policies []labelPolicy
+}
+
+// newLabelPolicies creates the label and annotation injection policies configured in 'input.policies'
+func newLabelPolicies(config *koanf.Koanf) (*labelPolicies, error) {
+	policies := &labelPolicies{}
+	if err := config.Unmarshal("input.policies", &policies.policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (lp *labelPolicies) transform(file string, object *unstructured.Unstructured) error {
+	for _, policy := range lp.policies {
+		if !policy.appliesTo(file, object) {
+			continue
+		}
+		if err := policy.inject(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *labelPolicy) appliesTo(file string, object *unstructured.Unstructured) bool {
+	if !inDirectory(file, p.Directory) {
+		return false
+	}
+	if p.Namespace != "" && p.Namespace != object.GetNamespace() {
+		return false
+	}
+	return true
+}
+
+func (p *labelPolicy) inject(object *unstructured.Unstructured) error {
+	if err := p.injectInto(object.Object, p.Labels, "metadata", "labels"); err != nil {
+		return err
+	}
+	if err := p.injectInto(object.Object, p.Annotations, "metadata", "annotations"); err != nil {
+		return err
+	}
+
+	if p.PodTemplates {
+		for _, template := range podTemplatePaths {
+			if _, found, _ := unstructured.NestedMap(object.Object, template...); !found {
+				continue
+			}
+			if err := p.injectInto(object.Object, p.Labels, append(template, "metadata", "labels")...); err != nil {
+				return err
+			}
+			if err := p.injectInto(object.Object, p.Annotations, append(template, "metadata", "annotations")...); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Selectors {
+		if object.GetKind() == "Service" {
+			return p.injectInto(object.Object, p.Labels, "spec", "selector")
+		}
+		if _, found, _ := unstructured.NestedMap(object.Object, "spec", "selector"); found {
+			return p.injectInto(object.Object, p.Labels, "spec", "selector", "matchLabels")
+		}
+	}
+
+	return nil
+}
+
+// podTemplatePaths are the paths to the pod templates of the built-in workload kinds
+var podTemplatePaths = [][]string{
+	{"spec", "template"},
+	{"spec", "jobTemplate", "spec", "template"},
+}
+
+// injectInto sets the values in the string map at the path, and fails if a key is already set to a different value
+// unless the policy allows overwriting
+func (p *labelPolicy) injectInto(object map[string]any, values map[string]string, fields ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	existing, _, err := unstructured.NestedStringMap(object, fields...)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		existing = make(map[string]string)
+	}
+
+	for key, value := range values {
+		if current, found := existing[key]; found && current != value && !p.Overwrite {
+			return fmt.Errorf("%s %s is %q, but policy sets it to %q: %w", strings.Join(fields, "."), key, current, value, PolicyConflict)
+		}
+		existing[key] = value
+	}
+
+	return unstructured.SetNestedStringMap(object, existing, fields...)
+}
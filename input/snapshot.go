@@ -0,0 +1,344 @@
+package input
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+)
+
+// Revision describes the revision of the input files that are currently loaded
+type Revision struct {
+	Name     string    `json:"name,omitempty"`
+	Priority int       `json:"priority"`
+	Source   string    `json:"source"`
+	Ref      string    `json:"ref,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	LoadedAt time.Time `json:"loadedAt,omitempty"`
+}
+
+// snapshotter finds the latest revision of the input files of a snapshotSource, and writes the files at a revision to a
+// checkout directory
+type snapshotter interface {
+	latest() (string, error)
+	extract(revision, checkout string) error
+}
+
+// snapshotSource loads the input files from snapshots of a git repository or an archive. The latest revision is polled
+// for every interval, and the files at a new revision are written to a separate checkout and loaded all at once. The
+// checkout of the previous revision is removed once the new one has loaded.
+type snapshotSource struct {
+	input       *DirectoryInput
+	snapshotter snapshotter
+	directory   string
+	checkouts   string
+	checkout    string
+	revision    Revision
+	gaveUp      bool
+	synced      *resources.SyncSignal
+	queue       *retry.Queue
+	mutex       sync.RWMutex
+	logger      *zerolog.Logger
+}
+
+// newSnapshotSource creates a snapshotSource that loads the files within the directory of each snapshot of the
+// revision source, using checkouts in a new temporary directory
+func newSnapshotSource(config *koanf.Koanf, kind string, revision Revision, directory string, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*snapshotSource, error) {
+	checkouts, err := os.MkdirTemp("", "kokk-"+kind+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := newDirectoryInput(config, checkouts, converter, types, patcher, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotSource{
+		input:     input,
+		directory: filepath.Clean(directory),
+		checkouts: checkouts,
+		revision:  revision,
+		synced:    resources.NewSyncSignal(),
+		queue:     retry.NewQueue(config, kind, logger),
+		logger:    logger,
+	}, nil
+}
+
+// start polls the snapshotter for new revisions every interval, and loads them
+func (ss *snapshotSource) start(snapshotter snapshotter, interval time.Duration) {
+	ss.snapshotter = snapshotter
+	ss.queue.OnGiveUp(ss.giveUp)
+	go ss.queue.Run(ss.process)
+	go ss.poll(interval)
+}
+
+func (ss *snapshotSource) Get(id string) (*resources.Resource, error) {
+	return ss.input.Get(id)
+}
+
+func (ss *snapshotSource) List() []resources.Resource {
+	return ss.input.List()
+}
+
+// HasSynced returns true when the first revision has been loaded successfully, or given up on
+func (ss *snapshotSource) HasSynced() bool {
+	return ss.synced.HasSynced()
+}
+
+// Synced returns a channel that is closed when the source has synced
+func (ss *snapshotSource) Synced() <-chan struct{} {
+	return ss.synced.Synced()
+}
+
+// HasFailed returns true when the first revision was given up on, and no revision has been loaded since
+func (ss *snapshotSource) HasFailed() bool {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.checkout == "" && ss.gaveUp
+}
+
+// AddListener registers a resources.Listener that is notified when resources in the repository change
+func (ss *snapshotSource) AddListener(listener resources.Listener) {
+	ss.input.AddListener(listener)
+}
+
+// ListFailures returns the failures to load the latest revision
+func (ss *snapshotSource) ListFailures() []retry.Failure {
+	return ss.queue.ListFailures()
+}
+
+// Revision returns the revision of the loaded input files
+func (ss *snapshotSource) Revision() Revision {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	return ss.revision
+}
+
+func (ss *snapshotSource) poll(interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ss.queue.Add(ss.revision.Source)
+	for range time.Tick(interval) {
+		ss.queue.Add(ss.revision.Source)
+	}
+}
+
+func (ss *snapshotSource) process(_ []string) map[string]error {
+	if err := ss.update(); err != nil {
+		return map[string]error{ss.revision.Source: err}
+	}
+	return nil
+}
+
+// giveUp stops waiting for the first revision to load, so that the source is synced but failed until a revision loads
+func (ss *snapshotSource) giveUp(_ string) {
+	ss.mutex.Lock()
+	ss.gaveUp = true
+	ss.mutex.Unlock()
+
+	ss.synced.MarkSynced()
+}
+
+// update loads the latest revision, if it is not already loaded
+func (ss *snapshotSource) update() error {
+	logger := ss.logger.With().Str("method", "update").Logger()
+
+	revision, err := ss.snapshotter.latest()
+	if err != nil {
+		return err
+	}
+
+	ss.mutex.RLock()
+	loaded := ss.revision.Commit
+	previous := ss.checkout
+	ss.mutex.RUnlock()
+	if revision == loaded {
+		return nil
+	}
+
+	logger = logger.With().Str("revision", revision).Logger()
+	checkout := filepath.Join(ss.checkouts, revision)
+	if err := ss.snapshotter.extract(revision, checkout); err != nil {
+		logger.Error().Err(err).Msg("Could not extract revision")
+		_ = os.RemoveAll(checkout)
+		return err
+	}
+
+	if err := ss.input.reload(filepath.Join(checkout, ss.directory), revision); err != nil {
+		_ = os.RemoveAll(checkout)
+		return err
+	}
+
+	ss.mutex.Lock()
+	ss.checkout = checkout
+	ss.revision.Commit = revision
+	ss.revision.LoadedAt = time.Now()
+	ss.mutex.Unlock()
+	ss.synced.MarkSynced()
+
+	if previous != "" && previous != checkout {
+		_ = os.RemoveAll(previous)
+	}
+
+	logger.Info().Str("previous", loaded).Msg("Loaded new revision")
+	return nil
+}
+
+// Revision returns the revision of the loaded input files. Files loaded from a directory that is watched for changes
+// have no commit.
+func (di *DirectoryInput) Revision() Revision {
+	di.mutex.RLock()
+	defer di.mutex.RUnlock()
+
+	return Revision{
+		Source: di.path,
+		Commit: di.revision,
+	}
+}
+
+// reload loads all the files within the root directory at the revision, and replaces all the loaded files at once. The
+// files that generators read are found first, and are not loaded as input files. If any of the other files fail to
+// load, the previously loaded files are kept.
+func (di *DirectoryInput) reload(root, revision string) error {
+	logger := di.logger.With().Str("method", "reload").Str("root", root).Str("revision", revision).Logger()
+
+	di.mutex.Lock()
+	previousPath := di.path
+	di.path = root
+	di.mutex.Unlock()
+
+	files, patches, sources, err := di.loadAll(root)
+	if err != nil {
+		di.mutex.Lock()
+		di.path = previousPath
+		di.mutex.Unlock()
+		logger.Error().Err(err).Msg("Failed to load revision")
+		return err
+	}
+
+	previous := make([]ingredient, 0)
+	current := make([]ingredient, 0)
+	di.mutex.Lock()
+	for _, ingredients := range di.files {
+		previous = append(previous, ingredients...)
+	}
+	for _, ingredients := range files {
+		current = append(current, ingredients...)
+	}
+	di.keepGenerations(previous, current)
+	di.files = files
+	di.patches = patches
+	di.sources = sources
+	di.revision = revision
+	di.mutex.Unlock()
+
+	logger.Debug().Int("files", len(files)+len(patches)).Msg("Loaded revision")
+	return di.rebuildAll()
+}
+
+// loadAll loads all the included files within the root directory, except the files that generators read
+func (di *DirectoryInput) loadAll(root string) (map[string][]ingredient, map[string][]*jsonPatch, map[string]string, error) {
+	names, err := di.includedFiles(root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	generatorSources := make(map[string]bool)
+	for _, name := range names {
+		for _, source := range di.generatorSources(name) {
+			generatorSources[source] = true
+		}
+	}
+
+	files := make(map[string][]ingredient)
+	patches := make(map[string][]*jsonPatch)
+	sources := make(map[string]string)
+	for _, name := range names {
+		if generatorSources[name] {
+			continue
+		}
+
+		ingredients, filePatches, fileSources, err := di.load(name)
+		if err != nil {
+			relative, _ := di.relativePath(name)
+			return nil, nil, nil, fmt.Errorf("file %s: %w", relative, err)
+		}
+		if len(ingredients) > 0 {
+			files[name] = ingredients
+		}
+		if len(filePatches) > 0 {
+			patches[name] = filePatches
+		}
+		for _, source := range fileSources {
+			sources[source] = name
+		}
+	}
+	return files, patches, sources, nil
+}
+
+// includedFiles returns all the files within the root directory that are included by the filter
+func (di *DirectoryInput) includedFiles(root string) ([]string, error) {
+	names := make([]string, 0)
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := di.relativePath(name)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if !di.filter.includesDirectory(relative) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if di.filter.includesFile(relative) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// generatorSources returns the files read by the generators in the file. Files that cannot be read or parsed have no
+// generators, and fail when they are loaded unless they are read by a generator themselves.
+func (di *DirectoryInput) generatorSources(name string) []string {
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	if isTemplate(name) {
+		if contents, err = di.renderer.render(name, contents); err != nil {
+			return nil
+		}
+	}
+	objects, err := parseDocuments(contents)
+	if err != nil {
+		return nil
+	}
+
+	sources := make([]string, 0)
+	for _, object := range objects {
+		if !isKokkKind(object) {
+			continue
+		}
+		if kind := object.GetKind(); kind == ConfigMapGeneratorKind || kind == SecretGeneratorKind {
+			sources = append(sources, generatorFiles(name, object)...)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,142 @@
+package input
+
+import (
+	"fmt"
+
+	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/rs/zerolog"
+)
+
+// Source is a repository of the resources loaded from input files
+type Source interface {
+	Get(id string) (*resources.Resource, error)
+	List() []resources.Resource
+	AddListener(listener resources.Listener)
+	HasSynced() bool
+	Synced() <-chan struct{}
+	HasFailed() bool
+	ListFailures() []retry.Failure
+	Revision() Revision
+}
+
+const (
+	DirectorySource = "directory"
+	GitSource       = "git"
+	ArchiveSource   = "archive"
+)
+
+// sourceConfig is a named input source configured in 'input.sources'. The path is the directory, git repository or
+// archive to load the files from, and the directory is the directory within the git repository or archive. The
+// transformers and policies of the source replace 'input.transformers' and 'input.policies', since their directories
+// are relative to the root of each source.
+type sourceConfig struct {
+	Name         string `koanf:"name"`
+	Kind         string `koanf:"kind"`
+	Priority     int    `koanf:"priority"`
+	Path         string `koanf:"path"`
+	Ref          string `koanf:"ref"`
+	Directory    string `koanf:"directory"`
+	Fetch        bool   `koanf:"fetch"`
+	Interval     string `koanf:"interval"`
+	Transformers []any  `koanf:"transformers"`
+	Policies     []any  `koanf:"policies"`
+}
+
+// overrides returns the configuration keys of the source kind that are set by the source
+func (sc *sourceConfig) overrides() (map[string]any, error) {
+	overrides := make(map[string]any)
+	set := func(key, value string) {
+		if value != "" {
+			overrides[key] = value
+		}
+	}
+
+	switch sc.Kind {
+	case DirectorySource:
+		set("input.directory", sc.Path)
+	case GitSource:
+		set("input.git.repository", sc.Path)
+		set("input.git.ref", sc.Ref)
+		set("input.git.directory", sc.Directory)
+		set("input.git.interval", sc.Interval)
+		overrides["input.git.fetch"] = sc.Fetch
+	case ArchiveSource:
+		set("input.archive.path", sc.Path)
+		set("input.archive.directory", sc.Directory)
+		set("input.archive.interval", sc.Interval)
+	default:
+		return nil, fmt.Errorf("source %s: %w: %q", sc.Name, UnknownSourceKind, sc.Kind)
+	}
+
+	if sc.Transformers != nil {
+		overrides["input.transformers"] = sc.Transformers
+	}
+	if sc.Policies != nil {
+		overrides["input.policies"] = sc.Policies
+	}
+	return overrides, nil
+}
+
+// NewSources creates the input sources configured in 'input.sources', combined into one repository. If no sources are
+// configured, a single source named 'default' is created from 'input.git.repository' if it is set, or from
+// 'input.directory' otherwise.
+func NewSources(config *koanf.Koanf, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (*CompositeInput, error) {
+	configs := make([]sourceConfig, 0)
+	if err := config.Unmarshal("input.sources", &configs); err != nil {
+		return nil, err
+	}
+
+	if len(configs) == 0 {
+		kind := DirectorySource
+		if config.String("input.git.repository") != "" {
+			kind = GitSource
+		}
+		loggerWithSource := logger.With().Str("source", "default").Logger()
+		source, err := newSource(kind, config, converter, types, patcher, &loggerWithSource)
+		if err != nil {
+			return nil, err
+		}
+		return NewCompositeInput([]NamedSource{{Name: "default", Source: source}}, logger), nil
+	}
+
+	sources := make([]NamedSource, 0, len(configs))
+	seen := make(map[string]bool)
+	for _, sc := range configs {
+		if sc.Name == "" || seen[sc.Name] {
+			return nil, fmt.Errorf("%w: sources require unique names", InvalidSource)
+		}
+		seen[sc.Name] = true
+
+		overrides, err := sc.overrides()
+		if err != nil {
+			return nil, err
+		}
+		sourceConfig := config.Copy()
+		if err := sourceConfig.Load(confmap.Provider(overrides, "."), nil); err != nil {
+			return nil, err
+		}
+
+		loggerWithSource := logger.With().Str("source", sc.Name).Logger()
+		source, err := newSource(sc.Kind, sourceConfig, converter, types, patcher, &loggerWithSource)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", sc.Name, err)
+		}
+		sources = append(sources, NamedSource{Name: sc.Name, Priority: sc.Priority, Source: source})
+	}
+
+	return NewCompositeInput(sources, logger), nil
+}
+
+func newSource(kind string, config *koanf.Koanf, converter TypeConverter, types TypeProvider, patcher Patcher, logger *zerolog.Logger) (Source, error) {
+	switch kind {
+	case GitSource:
+		return NewGitInput(config, converter, types, patcher, logger)
+	case ArchiveSource:
+		return NewArchiveInput(config, converter, types, patcher, logger)
+	default:
+		return NewDirectoryInput(config, converter, types, patcher, logger)
+	}
+}
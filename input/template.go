@@ -0,0 +1,65 @@
+package input
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/file"
+)
+
+// TemplateExtension is the file extension of input files that are rendered as Go templates before they are parsed
+const TemplateExtension = ".tmpl"
+
+type templateRenderer struct {
+	variables map[string]any
+	strict    bool
+}
+
+// newTemplateRenderer creates a renderer using the variables from 'input.variables', overridden by the variables in
+// the YAML files listed in 'input.valueFiles' in order. When 'input.strict' is set, rendering a template that
+// references a missing variable fails.
+func newTemplateRenderer(config *koanf.Koanf) (*templateRenderer, error) {
+	variables := koanf.New(".")
+	if err := variables.Load(confmap.Provider(config.Cut("input.variables").Raw(), "."), nil); err != nil {
+		return nil, err
+	}
+
+	for _, valueFile := range config.Strings("input.valueFiles") {
+		if err := variables.Load(file.Provider(valueFile), yaml.Parser()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &templateRenderer{
+		variables: variables.Raw(),
+		strict:    config.Bool("input.strict"),
+	}, nil
+}
+
+func isTemplate(name string) bool {
+	return strings.HasSuffix(name, TemplateExtension)
+}
+
+// render executes the file contents as a Go template with the configured variables
+func (tr *templateRenderer) render(name string, contents []byte) ([]byte, error) {
+	missingKey := "missingkey=default"
+	if tr.strict {
+		missingKey = "missingkey=error"
+	}
+
+	parsed, err := template.New(filepath.Base(name)).Option(missingKey).Parse(string(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	if err := parsed.Execute(&rendered, tr.variables); err != nil {
+		return nil, err
+	}
+	return rendered.Bytes(), nil
+}
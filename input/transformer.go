@@ -0,0 +1,87 @@
+package input
+
+import (
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// fileTransformer mutates the objects parsed from an input file, before their resource IDs are computed. The file is
+// the path of the input file relative to the input directory.
+type fileTransformer interface {
+	transform(file string, object *unstructured.Unstructured) error
+}
+
+// transform runs all the file transformers on the object in order
+func (di *DirectoryInput) transform(name string, object *unstructured.Unstructured) error {
+	file, err := di.relativePath(name)
+	if err != nil {
+		return err
+	}
+
+	for _, transformer := range di.transformers {
+		if err := transformer.transform(file, object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inDirectory returns true if the file, relative to the input directory, is within the directory. All files are within
+// an empty directory.
+func inDirectory(file, directory string) bool {
+	if directory == "" {
+		return true
+	}
+	directory = path.Clean(directory)
+	return directory == "." || strings.HasPrefix(path.Dir(file)+"/", directory+"/")
+}
+
+// applyLabels adds the labels to the object, to its selector, and to the labels of its pod templates. The selector of a
+// Service is a map of labels, while other objects select by 'matchLabels'.
+func applyLabels(object *unstructured.Unstructured, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	if err := mergeStringMap(object.Object, labels, "metadata", "labels"); err != nil {
+		return err
+	}
+	selector := []string{"spec", "selector", "matchLabels"}
+	if object.GetKind() == "Service" {
+		selector = []string{"spec", "selector"}
+	}
+	if _, found, _ := unstructured.NestedMap(object.Object, selector...); found {
+		if err := mergeStringMap(object.Object, labels, selector...); err != nil {
+			return err
+		}
+	}
+	for _, template := range podTemplatePaths {
+		if _, found, _ := unstructured.NestedMap(object.Object, template...); !found {
+			continue
+		}
+		if err := mergeStringMap(object.Object, labels, append(template, "metadata", "labels")...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeStringMap(object map[string]any, values map[string]string, fields ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	existing, _, err := unstructured.NestedStringMap(object, fields...)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		existing = make(map[string]string)
+	}
+	for key, value := range values {
+		existing[key] = value
+	}
+	return unstructured.SetNestedStringMap(object, existing, fields...)
+}
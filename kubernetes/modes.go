@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knadh/koanf"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Mode selects how Kokk reacts when an object in the cluster diverges from the input.
+type Mode string
+
+const (
+	// Enforce reverts the object in the cluster to the input
+	Enforce Mode = "enforce"
+	// Report only reports the object as drifted
+	Report Mode = "report"
+	// Ignore neither reverts nor reports the object as drifted
+	Ignore Mode = "ignore"
+)
+
+type Modes struct {
+	defaultMode Mode
+	kinds       map[string]Mode
+}
+
+// NewModes creates Modes using the default mode from 'kubernetes.mode', and the per-kind overrides from
+// 'kubernetes.modes'
+func NewModes(config *koanf.Koanf) (*Modes, error) {
+	modes := &Modes{
+		defaultMode: Mode(config.String("kubernetes.mode")),
+		kinds:       make(map[string]Mode),
+	}
+
+	if err := validateMode(modes.defaultMode); err != nil {
+		return nil, err
+	}
+
+	for kind, mode := range config.StringMap("kubernetes.modes") {
+		if err := validateMode(Mode(mode)); err != nil {
+			return nil, err
+		}
+		modes.kinds[strings.ToLower(kind)] = Mode(mode)
+	}
+
+	return modes, nil
+}
+
+// ModeFor returns the Mode configured for the kind, or the default Mode
+func (m *Modes) ModeFor(gvk schema.GroupVersionKind) Mode {
+	if mode, found := m.kinds[strings.ToLower(gvk.Kind)]; found {
+		return mode
+	}
+	return m.defaultMode
+}
+
+func validateMode(mode Mode) error {
+	switch mode {
+	case Enforce, Report, Ignore:
+		return nil
+	}
+	return fmt.Errorf("the configured mode %s is not supported, must be '%s', '%s' or '%s'", mode, Enforce, Report, Ignore)
+}
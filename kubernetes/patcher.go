@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+const groupVersionKindExtension = "x-kubernetes-group-version-kind"
+
+type Patcher struct {
+	schemas map[schema.GroupVersionKind]proto.Schema
+	logger  *zerolog.Logger
+}
+
+// NewPatcher creates a Patcher using the OpenAPI schemas published by the API server
+func NewPatcher(client discovery.OpenAPISchemaInterface, logger *zerolog.Logger) (*Patcher, error) {
+	document, err := client.OpenAPISchema()
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := proto.NewOpenAPIData(document)
+	if err != nil {
+		return nil, err
+	}
+
+	patcher := &Patcher{
+		schemas: make(map[schema.GroupVersionKind]proto.Schema),
+		logger:  logger,
+	}
+
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		for _, gvk := range groupVersionKindsOf(model) {
+			patcher.schemas[gvk] = model
+		}
+	}
+
+	logger.Debug().Int("schemas", len(patcher.schemas)).Msg("Loaded OpenAPI schemas")
+
+	return patcher, nil
+}
+
+// StrategicMergePatch applies the patch object to the original object as a Kubernetes strategic merge patch, using the
+// patch merge keys from the OpenAPI schema of the type. Types that are not built into Kubernetes, like custom
+// resources, do not support strategic merge patches, and are patched using a JSON merge patch instead.
+func (p *Patcher) StrategicMergePatch(original, patch *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	gvk := original.GroupVersionKind()
+
+	if model, found := p.schemas[gvk]; found && scheme.Scheme.Recognizes(gvk) {
+		patched, err := strategicpatch.StrategicMergeMapPatchUsingLookupPatchMeta(original.Object, patch.Object, strategicpatch.NewPatchMetaFromOpenAPI(model))
+		if err != nil {
+			return nil, err
+		}
+		return &unstructured.Unstructured{Object: patched}, nil
+	}
+
+	p.logger.Trace().Str("group", gvk.Group).Str("version", gvk.Version).Str("kind", gvk.Kind).Msg("No strategic merge schema for type, using JSON merge patch")
+	return JSONMergePatch(original, patch)
+}
+
+// JSONMergePatch applies the patch object to the original object as an RFC 7386 JSON merge patch
+func JSONMergePatch(original, patch *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	originalData, err := json.Marshal(original.Object)
+	if err != nil {
+		return nil, err
+	}
+	patchData, err := json.Marshal(patch.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedData, err := jsonpatch.MergePatch(originalData, patchData)
+	if err != nil {
+		return nil, err
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(patchedData); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// groupVersionKindsOf reads the GroupVersionKinds a model describes from its OpenAPI extension
+func groupVersionKindsOf(model proto.Schema) []schema.GroupVersionKind {
+	extension, found := model.GetExtensions()[groupVersionKindExtension]
+	if !found {
+		return nil
+	}
+
+	list, ok := extension.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	gvks := make([]schema.GroupVersionKind, 0, len(list))
+	for _, item := range list {
+		values, ok := item.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		group, _ := values["group"].(string)
+		version, _ := values["version"].(string)
+		kind, _ := values["kind"].(string)
+		gvks = append(gvks, schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+	}
+	return gvks
+}
@@ -1,9 +1,12 @@
 package output
 
 import (
+	"path"
+	"sync"
 	"time"
 
 	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
 	"github.com/knadh/koanf"
 	"github.com/rs/zerolog"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -36,11 +39,15 @@ func NewKubernetesOutput(config *koanf.Koanf, types TypeDiscoverer, converter Ty
 		handler: kubernetesOutputHandler{
 			repository: make(map[string]resources.Resource),
 			converter:  converter,
+			pending:    make(map[string]*unstructured.Unstructured),
+			queue:      retry.NewQueue(config, "output", logger),
 			logger:     logger,
 		},
 		logger: logger,
 	}
 
+	go output.handler.queue.Run(output.handler.retryPending)
+
 	if err := output.startInformers(client); err != nil {
 		return nil, err
 	}
@@ -49,6 +56,9 @@ func NewKubernetesOutput(config *koanf.Koanf, types TypeDiscoverer, converter Ty
 }
 
 func (o *KubernetesOutput) Get(id string) (*resources.Resource, error) {
+	o.handler.mutex.RLock()
+	defer o.handler.mutex.RUnlock()
+
 	if resource, found := o.handler.repository[id]; found {
 		return &resource, nil
 	}
@@ -57,6 +67,9 @@ func (o *KubernetesOutput) Get(id string) (*resources.Resource, error) {
 }
 
 func (o *KubernetesOutput) List() []resources.Resource {
+	o.handler.mutex.RLock()
+	defer o.handler.mutex.RUnlock()
+
 	list := make([]resources.Resource, 0, len(o.handler.repository))
 	for _, resource := range o.handler.repository {
 		list = append(list, resource)
@@ -64,6 +77,19 @@ func (o *KubernetesOutput) List() []resources.Resource {
 	return list
 }
 
+// ListFailures returns the Kubernetes objects that failed to convert and are being retried, or have been given up on
+func (o *KubernetesOutput) ListFailures() []retry.Failure {
+	return o.handler.queue.ListFailures()
+}
+
+// AddListener registers a resources.Listener that is notified when resources in the repository change
+func (o *KubernetesOutput) AddListener(listener resources.Listener) {
+	o.handler.mutex.Lock()
+	defer o.handler.mutex.Unlock()
+
+	o.handler.listeners = append(o.handler.listeners, listener)
+}
+
 func (o *KubernetesOutput) startInformers(client dynamic.Interface) error {
 	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, time.Duration(o.resyncSeconds)*time.Second)
 	o.stop = make(chan struct{})
@@ -85,6 +111,10 @@ func (o *KubernetesOutput) startInformers(client dynamic.Interface) error {
 type kubernetesOutputHandler struct {
 	repository map[string]resources.Resource
 	converter  TypeConverter
+	pending    map[string]*unstructured.Unstructured
+	queue      *retry.Queue
+	listeners  []resources.Listener
+	mutex      sync.RWMutex
 	logger     *zerolog.Logger
 }
 
@@ -97,17 +127,56 @@ func (oh *kubernetesOutputHandler) OnAdd(obj interface{}) {
 		return
 	}
 
+	if err := oh.store(resource); err != nil {
+		key := pendingKeyFor(resource)
+		oh.mutex.Lock()
+		oh.pending[key] = resource
+		oh.mutex.Unlock()
+		oh.queue.Add(key)
+	}
+}
+
+// retryPending retries storing the objects that previously failed to convert
+func (oh *kubernetesOutputHandler) retryPending(keys []string) map[string]error {
+	errs := make(map[string]error)
+	for _, key := range keys {
+		oh.mutex.RLock()
+		resource, found := oh.pending[key]
+		oh.mutex.RUnlock()
+		if !found {
+			continue
+		}
+
+		if err := oh.store(resource); err != nil {
+			errs[key] = err
+		}
+	}
+	return errs
+}
+
+func (oh *kubernetesOutputHandler) store(resource *unstructured.Unstructured) error {
+	logger := oh.logger.With().Str("method", "store").Logger()
+
 	gvk := resource.GroupVersionKind()
 	logger = logger.With().Str("group", gvk.Group).Str("version", gvk.Version).Str("kind", gvk.Kind).Logger()
 
 	converted, err := oh.converter.Convert(resource)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to convert resource")
-		return
+		return err
 	}
 
+	oh.mutex.Lock()
 	oh.repository[converted.Id] = *converted
+	delete(oh.pending, pendingKeyFor(resource))
+	listeners := oh.listeners
+	oh.mutex.Unlock()
 	logger.Trace().Str("id", converted.Id).Msg("Added resource to repository")
+
+	for _, listener := range listeners {
+		listener.OnResourceUpdated(converted.Id)
+	}
+	return nil
 }
 
 func (oh *kubernetesOutputHandler) OnUpdate(_, newObj interface{}) {
@@ -126,12 +195,28 @@ func (oh *kubernetesOutputHandler) OnDelete(obj interface{}) {
 	gvk := resource.GroupVersionKind()
 	logger = logger.With().Str("group", gvk.Group).Str("version", gvk.Version).Str("kind", gvk.Kind).Logger()
 
+	oh.mutex.Lock()
+	delete(oh.pending, pendingKeyFor(resource))
+	oh.mutex.Unlock()
+
 	id, err := oh.converter.GetIdFor(resource)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to get id for resource")
 		return
 	}
 
+	oh.mutex.Lock()
 	delete(oh.repository, id)
+	listeners := oh.listeners
+	oh.mutex.Unlock()
 	logger.Trace().Str("id", id).Msg("Removed resource from repository")
+
+	for _, listener := range listeners {
+		listener.OnResourceRemoved(id)
+	}
+}
+
+// pendingKeyFor returns the key used to retry an object that could not be converted, and therefore has no resource ID
+func pendingKeyFor(resource *unstructured.Unstructured) string {
+	return path.Join(resource.GroupVersionKind().String(), resource.GetNamespace(), resource.GetName())
 }
@@ -0,0 +1,7 @@
+package reconcile
+
+import "errors"
+
+var (
+	ResultNotFound = errors.New("result not found")
+)
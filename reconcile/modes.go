@@ -0,0 +1,38 @@
+package reconcile
+
+import (
+	"dolittle.io/kokk/diff"
+	"dolittle.io/kokk/kubernetes"
+	"dolittle.io/kokk/resources"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type ModeProvider interface {
+	ModeFor(gvk schema.GroupVersionKind) kubernetes.Mode
+}
+
+type Differ interface {
+	Diff(desired, live *unstructured.Unstructured) []diff.Change
+}
+
+// enforceIfDrifted queues the resource to be applied again if the live object has diverged from the input, and the
+// kind is configured to be enforced
+func (r *Reconciler) enforceIfDrifted(resource *resources.Resource, live *unstructured.Unstructured) {
+	if r.modes.ModeFor(live.GroupVersionKind()) != kubernetes.Enforce {
+		return
+	}
+
+	desired := &unstructured.Unstructured{}
+	if err := desired.UnmarshalJSON(resource.Content); err != nil {
+		return
+	}
+
+	changes := r.differ.Diff(desired, live)
+	if len(changes) == 0 {
+		return
+	}
+
+	r.logger.Info().Str("id", resource.Id).Int("changes", len(changes)).Msg("Resource has drifted in the cluster, reverting")
+	r.enqueue(resource.Id)
+}
@@ -0,0 +1,34 @@
+package reconcile
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+const (
+	// ManagedByLabel is the label set on every object applied by Kokk
+	ManagedByLabel = "kokk.dolittle.io/managed-by"
+	// ManagedByValue is the value of the ManagedByLabel on objects applied by Kokk
+	ManagedByValue = "kokk"
+	// IdAnnotation is the annotation that records the resource ID an object was applied from
+	IdAnnotation = "kokk.dolittle.io/id"
+)
+
+// markAsManaged sets the ownership label and annotation on the object
+func markAsManaged(object *unstructured.Unstructured, id string) {
+	labels := object.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ManagedByLabel] = ManagedByValue
+	object.SetLabels(labels)
+
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[IdAnnotation] = id
+	object.SetAnnotations(annotations)
+}
+
+// isManaged checks whether the object carries the ownership label and annotation for the resource ID
+func isManaged(object *unstructured.Unstructured, id string) bool {
+	return object.GetLabels()[ManagedByLabel] == ManagedByValue && object.GetAnnotations()[IdAnnotation] == id
+}
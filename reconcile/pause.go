@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PausedAnnotation is the annotation that stops Kokk from writing to a resource when set to "true" on either the input
+// file or the live object
+const PausedAnnotation = "kokk.dolittle.io/paused"
+
+// Pause stops the reconciler from writing to any resource until Resume is called. Paused resources are still tracked
+// and diffed.
+func (r *Reconciler) Pause() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.paused = true
+	r.logger.Warn().Msg("Reconciliation paused")
+}
+
+// Resume lets the reconciler write to resources again, and queues all resources in the input and inventory
+func (r *Reconciler) Resume() {
+	r.mutex.Lock()
+	r.paused = false
+	r.mutex.Unlock()
+	r.logger.Info().Msg("Reconciliation resumed")
+
+	for _, resource := range r.input.List() {
+		r.enqueue(resource.Id)
+	}
+	for _, id := range r.ListManaged() {
+		r.enqueue(id)
+	}
+}
+
+func (r *Reconciler) IsPaused() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.paused
+}
+
+// isPaused checks whether the reconciler is paused, or the resource is paused by the PausedAnnotation on the input or
+// the live object
+func (r *Reconciler) isPaused(id string) bool {
+	if r.IsPaused() {
+		return true
+	}
+
+	if resource, err := r.input.Get(id); err == nil {
+		object := &unstructured.Unstructured{}
+		if err := object.UnmarshalJSON(resource.Content); err == nil && hasPausedAnnotation(object) {
+			return true
+		}
+	}
+
+	if live, err := r.getLiveObject(id); err == nil && hasPausedAnnotation(live) {
+		return true
+	}
+
+	return false
+}
+
+// resumeIfUnpaused queues a resource that was skipped while paused, if it is no longer paused
+func (r *Reconciler) resumeIfUnpaused(id string) {
+	r.mutex.RLock()
+	result, found := r.results[id]
+	r.mutex.RUnlock()
+
+	if found && result.Outcome == Paused && !r.isPaused(id) {
+		r.enqueue(id)
+	}
+}
+
+func (r *Reconciler) markAsPaused(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.results[id] = Result{
+		Id:        id,
+		Outcome:   Paused,
+		DryRun:    r.dryRun,
+		Timestamp: time.Now(),
+	}
+}
+
+func hasPausedAnnotation(object *unstructured.Unstructured) bool {
+	return object.GetAnnotations()[PausedAnnotation] == "true"
+}
@@ -0,0 +1,177 @@
+package reconcile
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ListManaged returns the IDs of all resources in the inventory of objects managed by Kokk
+func (r *Reconciler) ListManaged() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ids := make([]string, 0, len(r.inventory))
+	for id := range r.inventory {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// outputListener adds managed objects observed in the cluster to the inventory, queues those that are no longer in the
+// input for pruning, and those that have drifted from the input to be enforced
+type outputListener struct {
+	reconciler *Reconciler
+}
+
+func (ol *outputListener) OnResourceUpdated(id string) {
+	ol.reconciler.onOutputUpdated(id)
+}
+
+func (ol *outputListener) OnResourceRemoved(id string) {
+	if _, err := ol.reconciler.input.Get(id); err != nil {
+		ol.reconciler.enqueue(id)
+	}
+}
+
+func (r *Reconciler) onOutputUpdated(id string) {
+	live, err := r.getLiveObject(id)
+	if err != nil {
+		return
+	}
+
+	managed := isManaged(live, id)
+	if managed {
+		r.mutex.Lock()
+		r.inventory[id] = struct{}{}
+		r.mutex.Unlock()
+	}
+
+	r.resumeIfUnpaused(id)
+
+	resource, err := r.input.Get(id)
+	if err != nil {
+		if managed && r.canPrune() {
+			r.enqueue(id)
+		}
+		return
+	}
+
+	r.enforceIfDrifted(resource, live)
+}
+
+// canPrune returns true when the input has synced and has not failed. Until then, resources missing from the input
+// might just not have been loaded, so nothing is pruned.
+func (r *Reconciler) canPrune() bool {
+	return r.input.HasSynced() && !r.input.HasFailed()
+}
+
+// pruneWhenSynced waits for the input to sync, and then queues the managed resources that are not in the input for
+// pruning. If the input has failed, they are left to be queued when the live objects are resynced after it loads.
+func (r *Reconciler) pruneWhenSynced() {
+	<-r.input.Synced()
+	if !r.canPrune() {
+		r.logger.Warn().Msg("Input synced with failures, pruning is disabled until it loads")
+		return
+	}
+	r.logger.Info().Msg("Input synced, pruning is enabled")
+
+	for _, id := range r.ListManaged() {
+		if _, err := r.input.Get(id); err != nil {
+			r.enqueue(id)
+		}
+	}
+}
+
+// pruneIfManaged deletes the object with the resource ID from the cluster if it is in the inventory, and the live
+// object is still marked as managed by Kokk. Unmanaged objects are never touched.
+func (r *Reconciler) pruneIfManaged(id string) {
+	logger := r.logger.With().Str("method", "pruneIfManaged").Str("id", id).Logger()
+
+	r.mutex.RLock()
+	_, inInventory := r.inventory[id]
+	r.mutex.RUnlock()
+
+	live, err := r.getLiveObject(id)
+	if err != nil || !inInventory || !isManaged(live, id) {
+		r.forget(id)
+		logger.Trace().Msg("Resource is not in the input nor managed in the cluster, forgetting")
+		return
+	}
+
+	if !r.prune {
+		r.forget(id)
+		logger.Debug().Msg("Pruning is disabled, leaving managed resource in cluster")
+		return
+	}
+
+	result := Result{
+		Id:        id,
+		Outcome:   Pruned,
+		DryRun:    r.dryRun,
+		Timestamp: time.Now(),
+	}
+	if err := r.delete(live); err != nil {
+		result.Outcome = Failed
+		result.Error = err.Error()
+		logger.Error().Err(err).Msg("Failed to prune resource")
+	} else {
+		logger.Info().Str("propagation", string(r.propagationPolicy)).Msg("Pruned resource")
+	}
+
+	r.mutex.Lock()
+	r.results[id] = result
+	delete(r.dryRuns, id)
+	if result.Outcome == Pruned && !r.dryRun {
+		delete(r.inventory, id)
+	}
+	r.mutex.Unlock()
+}
+
+func (r *Reconciler) delete(live *unstructured.Unstructured) error {
+	client, err := r.resourceInterfaceFor(live)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	uid := live.GetUID()
+	err = client.Delete(ctx, live.GetName(), metav1.DeleteOptions{
+		PropagationPolicy: &r.propagationPolicy,
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+		DryRun:            r.dryRunOption(),
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *Reconciler) getLiveObject(id string) (*unstructured.Unstructured, error) {
+	resource, err := r.output.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(resource.Content); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (r *Reconciler) forget(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.results, id)
+	delete(r.dryRuns, id)
+	delete(r.inventory, id)
+}
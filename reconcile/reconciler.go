@@ -0,0 +1,341 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dolittle.io/kokk/resources"
+	"dolittle.io/kokk/retry"
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// FieldManager is the server-side apply field manager used for all resources applied by Kokk
+const FieldManager = "kokk"
+
+type InputRepository interface {
+	List() []resources.Resource
+	Get(id string) (*resources.Resource, error)
+	AddListener(listener resources.Listener)
+	HasSynced() bool
+	Synced() <-chan struct{}
+	HasFailed() bool
+}
+
+type OutputRepository interface {
+	List() []resources.Resource
+	Get(id string) (*resources.Resource, error)
+	AddListener(listener resources.Listener)
+}
+
+type TypeProvider interface {
+	IsNamespaced(gvk schema.GroupVersionKind) (bool, error)
+	GetGroupVersionResource(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error)
+}
+
+type Reconciler struct {
+	input             InputRepository
+	output            OutputRepository
+	types             TypeProvider
+	modes             ModeProvider
+	differ            Differ
+	client            dynamic.Interface
+	timeout           time.Duration
+	dryRun            bool
+	prune             bool
+	propagationPolicy metav1.DeletionPropagation
+	recreateKinds     []string
+	recreateTimeout   time.Duration
+	queue             *retry.Queue
+	results           map[string]Result
+	dryRuns           map[string]resources.Resource
+	inventory         map[string]struct{}
+	paused            bool
+	mutex             sync.RWMutex
+	logger            *zerolog.Logger
+}
+
+func NewReconciler(config *koanf.Koanf, input InputRepository, output OutputRepository, types TypeProvider, modes ModeProvider, differ Differ, client dynamic.Interface, logger *zerolog.Logger) (*Reconciler, error) {
+	loggerWithComponent := logger.With().Str("component", "reconciler").Logger()
+
+	reconciler := &Reconciler{
+		input:             input,
+		output:            output,
+		types:             types,
+		modes:             modes,
+		differ:            differ,
+		client:            client,
+		timeout:           time.Duration(config.Int("reconcile.timeout")) * time.Second,
+		dryRun:            config.Bool("reconcile.dryRun"),
+		prune:             config.Bool("reconcile.prune"),
+		propagationPolicy: metav1.DeletionPropagation(config.String("reconcile.propagationPolicy")),
+		recreateKinds:     config.Strings("reconcile.recreate"),
+		recreateTimeout:   time.Duration(config.Int("reconcile.recreateTimeout")) * time.Second,
+		queue:             retry.NewQueue(config, "reconcile", &loggerWithComponent),
+		results:           make(map[string]Result),
+		dryRuns:           make(map[string]resources.Resource),
+		inventory:         make(map[string]struct{}),
+		logger:            &loggerWithComponent,
+	}
+
+	switch reconciler.propagationPolicy {
+	case metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan:
+	default:
+		return nil, fmt.Errorf("the configured propagation policy %s is not supported", reconciler.propagationPolicy)
+	}
+
+	if reconciler.dryRun {
+		reconciler.logger.Warn().Msg("Running in dry-run mode, no changes will be persisted")
+	}
+
+	input.AddListener(reconciler)
+	for _, resource := range input.List() {
+		reconciler.enqueue(resource.Id)
+	}
+
+	output.AddListener(&outputListener{reconciler})
+	for _, resource := range output.List() {
+		reconciler.onOutputUpdated(resource.Id)
+	}
+
+	go reconciler.queue.Run(reconciler.processWaves)
+	go reconciler.pruneWhenSynced()
+
+	return reconciler, nil
+}
+
+func (r *Reconciler) GetResult(id string) (*Result, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if result, found := r.results[id]; found {
+		r.withRetries(&result)
+		return &result, nil
+	}
+
+	return nil, ResultNotFound
+}
+
+func (r *Reconciler) ListResults() []Result {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	list := make([]Result, 0, len(r.results))
+	for _, result := range r.results {
+		r.withRetries(&result)
+		list = append(list, result)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// GetDryRun returns the object the API server would have stored for the resource, as returned by the
+// latest dry-run apply
+func (r *Reconciler) GetDryRun(id string) (*resources.Resource, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if resource, found := r.dryRuns[id]; found {
+		return &resource, nil
+	}
+
+	return nil, ResultNotFound
+}
+
+func (r *Reconciler) OnResourceUpdated(id string) {
+	r.enqueue(id)
+}
+
+func (r *Reconciler) OnResourceRemoved(id string) {
+	r.enqueue(id)
+}
+
+func (r *Reconciler) enqueue(id string) {
+	r.queue.Add(id)
+}
+
+// withRetries adds the number of retries of the resource to the result, and marks it as Failed if the queue has given
+// up retrying it
+func (r *Reconciler) withRetries(result *Result) {
+	failure, err := r.queue.GetFailure(result.Id)
+	if err != nil {
+		return
+	}
+
+	result.Retries = failure.Retries
+	if failure.GaveUp {
+		result.Outcome = Failed
+	}
+}
+
+func (r *Reconciler) reconcile(id string) {
+	logger := r.logger.With().Str("method", "reconcile").Str("id", id).Logger()
+
+	if r.isPaused(id) {
+		r.markAsPaused(id)
+		logger.Debug().Msg("Reconciliation is paused, skipping resource")
+		return
+	}
+
+	resource, err := r.input.Get(id)
+	if err != nil {
+		if r.canPrune() {
+			r.pruneIfManaged(id)
+		}
+		return
+	}
+
+	outcome, applied, err := r.apply(resource)
+	result := Result{
+		Id:        id,
+		Outcome:   outcome,
+		DryRun:    r.dryRun,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		if immutable, ok := err.(*ImmutableFieldsError); ok {
+			result.ImmutableFields = immutable.Fields
+		}
+		logger.Error().Err(err).Msg("Failed to apply resource")
+	} else {
+		logger.Debug().Str("outcome", string(outcome)).Msg("Applied resource")
+	}
+
+	r.mutex.Lock()
+	r.results[id] = result
+	if err == nil {
+		r.inventory[id] = struct{}{}
+	}
+	if r.dryRun && applied != nil {
+		if content, err := applied.MarshalJSON(); err == nil {
+			r.dryRuns[id] = resources.Resource{Id: id, Content: content}
+		}
+	}
+	r.mutex.Unlock()
+}
+
+func (r *Reconciler) apply(resource *resources.Resource) (Outcome, *unstructured.Unstructured, error) {
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(resource.Content); err != nil {
+		return Failed, nil, err
+	}
+	removeServerFields(object)
+	markAsManaged(object, resource.Id)
+
+	content, err := object.MarshalJSON()
+	if err != nil {
+		return Failed, nil, err
+	}
+
+	client, err := r.resourceInterfaceFor(object)
+	if err != nil {
+		return Failed, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	exists := true
+	existing, err := client.Get(ctx, object.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		exists = false
+	} else if err != nil {
+		return Failed, nil, err
+	}
+
+	force := true
+	applied, err := client.Patch(ctx, object.GetName(), types.ApplyPatchType, content, metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+		DryRun:       r.dryRunOption(),
+	})
+	if fields, immutable := immutableFieldsIn(err); immutable && exists {
+		if !r.shouldRecreate(object) {
+			return Failed, nil, &ImmutableFieldsError{Fields: fields, Err: err}
+		}
+
+		r.logger.Info().Str("id", resource.Id).Strs("fields", fields).Msg("Recreating resource to change immutable fields")
+		recreated, err := r.recreate(client, existing, content)
+		if err != nil {
+			return Failed, nil, err
+		}
+		return Recreated, recreated, nil
+	}
+	if err != nil {
+		return Failed, nil, err
+	}
+
+	if !exists {
+		return Created, applied, nil
+	}
+	if r.dryRun && equalIgnoringVersion(existing, applied) {
+		return Unchanged, applied, nil
+	}
+	if !r.dryRun && applied.GetResourceVersion() == existing.GetResourceVersion() {
+		return Unchanged, applied, nil
+	}
+	return Updated, applied, nil
+}
+
+// dryRunOption returns the DryRun option to set on every write request sent through the dynamic client
+func (r *Reconciler) dryRunOption() []string {
+	if r.dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// removeServerFields removes the fields that are set by the API server from the object, so that objects exported from a
+// cluster can be applied as they are
+func removeServerFields(object *unstructured.Unstructured) {
+	object.SetManagedFields(nil)
+	object.SetResourceVersion("")
+	object.SetUID("")
+	object.SetGeneration(0)
+	object.SetCreationTimestamp(metav1.Time{})
+	object.SetSelfLink("")
+	unstructured.RemoveNestedField(object.Object, "status")
+}
+
+// equalIgnoringVersion compares two objects, disregarding the fields a dry-run request updates even when nothing changes
+func equalIgnoringVersion(a, b *unstructured.Unstructured) bool {
+	a, b = a.DeepCopy(), b.DeepCopy()
+	for _, object := range []*unstructured.Unstructured{a, b} {
+		object.SetResourceVersion("")
+		object.SetManagedFields(nil)
+	}
+	return equality.Semantic.DeepEqual(a.Object, b.Object)
+}
+
+func (r *Reconciler) resourceInterfaceFor(object *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := object.GroupVersionKind()
+
+	gvr, err := r.types.GetGroupVersionResource(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaced, err := r.types.IsNamespaced(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespaced {
+		return r.client.Resource(gvr).Namespace(object.GetNamespace()), nil
+	}
+
+	return r.client.Resource(gvr), nil
+}
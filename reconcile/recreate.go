@@ -0,0 +1,112 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// RecreateAnnotation is the annotation that allows a resource to be deleted and created again when an apply changes
+// immutable fields
+const RecreateAnnotation = "kokk.dolittle.io/recreate"
+
+// ImmutableFieldsError is returned when an apply is rejected by the API server because it changes immutable fields
+type ImmutableFieldsError struct {
+	Fields []string
+	Err    error
+}
+
+func (e *ImmutableFieldsError) Error() string {
+	return fmt.Sprintf("cannot change immutable fields %s, set the %s annotation or configure 'reconcile.recreate' to recreate the resource: %s", strings.Join(e.Fields, ", "), RecreateAnnotation, e.Err)
+}
+
+func (e *ImmutableFieldsError) Unwrap() error {
+	return e.Err
+}
+
+// immutableFieldsIn returns the fields an Invalid error from the API server rejected as immutable
+func immutableFieldsIn(err error) ([]string, bool) {
+	if !errors.IsInvalid(err) {
+		return nil, false
+	}
+
+	status, ok := err.(errors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return nil, false
+	}
+
+	fields := make([]string, 0)
+	for _, cause := range status.Status().Details.Causes {
+		if strings.Contains(cause.Message, "immutable") {
+			fields = append(fields, cause.Field)
+		}
+	}
+	return fields, len(fields) > 0
+}
+
+// shouldRecreate checks whether the object is annotated with the RecreateAnnotation, or its kind is configured in
+// 'reconcile.recreate'
+func (r *Reconciler) shouldRecreate(object *unstructured.Unstructured) bool {
+	if value, found := object.GetAnnotations()[RecreateAnnotation]; found {
+		return value == "true"
+	}
+
+	for _, kind := range r.recreateKinds {
+		if strings.EqualFold(kind, object.GetKind()) {
+			return true
+		}
+	}
+	return false
+}
+
+// recreate deletes the existing object, waits for it to be fully removed from the cluster, and applies the content
+// again. In dry-run mode only the delete is sent, as the object would still exist when it is applied.
+func (r *Reconciler) recreate(client dynamic.ResourceInterface, existing *unstructured.Unstructured, content []byte) (*unstructured.Unstructured, error) {
+	logger := r.logger.With().Str("method", "recreate").Str("kind", existing.GetKind()).Str("name", existing.GetName()).Logger()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.recreateTimeout)
+	defer cancel()
+
+	uid := existing.GetUID()
+	err := client.Delete(ctx, existing.GetName(), metav1.DeleteOptions{
+		PropagationPolicy: &r.propagationPolicy,
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+		DryRun:            r.dryRunOption(),
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if r.dryRun {
+		return nil, nil
+	}
+
+	logger.Debug().Msg("Waiting for resource to be deleted before recreating")
+	err = wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		current, err := client.Get(ctx, existing.GetName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return current.GetUID() != uid, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for resource to be deleted before recreating: %w", err)
+	}
+
+	force := true
+	return client.Patch(ctx, existing.GetName(), types.ApplyPatchType, content, metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	})
+}
@@ -0,0 +1,28 @@
+package reconcile
+
+import "time"
+
+// Outcome describes what happened when a resource was applied to, or pruned from, the cluster.
+type Outcome string
+
+const (
+	Created   Outcome = "Created"
+	Updated   Outcome = "Updated"
+	Unchanged Outcome = "Unchanged"
+	Recreated Outcome = "Recreated"
+	Pruned    Outcome = "Pruned"
+	Pending   Outcome = "Pending"
+	Paused    Outcome = "Paused"
+	Failed    Outcome = "Failed"
+)
+
+// Result records the outcome of the latest apply or prune of a resource.
+type Result struct {
+	Id              string    `json:"id"`
+	Outcome         Outcome   `json:"outcome"`
+	Error           string    `json:"error,omitempty"`
+	ImmutableFields []string  `json:"immutableFields,omitempty"`
+	DryRun          bool      `json:"dryRun,omitempty"`
+	Retries         int       `json:"retries,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
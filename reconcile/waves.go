@@ -0,0 +1,174 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WaveAnnotation is the annotation that overrides the wave a resource is applied in
+const WaveAnnotation = "kokk.dolittle.io/wave"
+
+// DefaultWave is the wave of resources without a WaveAnnotation, and of a kind that is not in KindWaves
+const DefaultWave = 10
+
+// KindWaves are the waves resources of these kinds are applied in when they have no WaveAnnotation, so that resources
+// are created before other resources that depend on them
+var KindWaves = map[string]int{
+	"Namespace":                0,
+	"CustomResourceDefinition": 1,
+	"ServiceAccount":           2,
+	"ClusterRole":              3,
+	"Role":                     3,
+	"ClusterRoleBinding":       4,
+	"RoleBinding":              4,
+	"ConfigMap":                5,
+	"Secret":                   5,
+}
+
+type wave struct {
+	number int
+	ids    []string
+}
+
+// processWaves applies the resources that are in the input wave by wave in ascending order, and waits for every apply
+// in a wave to be accepted by the API server before starting on the next. If any apply in a wave fails, the
+// following waves are left pending. Resources that are not in the input are then pruned in the reverse order, once the
+// input has synced. The errors of the failed and pending resources are returned so that they are retried.
+func (r *Reconciler) processWaves(ids []string) map[string]error {
+	applies, prunes := r.planWaves(ids)
+
+	for i, current := range applies {
+		r.logger.Trace().Int("wave", current.number).Strs("ids", current.ids).Msg("Applying wave")
+		if r.runWave(current) {
+			continue
+		}
+
+		for _, blocked := range applies[i+1:] {
+			r.markAsPending(blocked, fmt.Sprintf("waiting for wave %d to be applied", current.number))
+		}
+		break
+	}
+
+	for i := len(prunes) - 1; i >= 0; i-- {
+		r.logger.Trace().Int("wave", prunes[i].number).Strs("ids", prunes[i].ids).Msg("Pruning wave")
+		r.runWave(prunes[i])
+	}
+
+	return r.errorsFor(ids)
+}
+
+func (r *Reconciler) errorsFor(ids []string) map[string]error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	errs := make(map[string]error)
+	for _, id := range ids {
+		if result, found := r.results[id]; found && (result.Outcome == Failed || result.Outcome == Pending) {
+			errs[id] = errors.New(result.Error)
+		}
+	}
+	return errs
+}
+
+// planWaves groups the resources to apply by the waves of the input objects, and the resources to prune by the waves
+// of the live objects, sorted in ascending order
+func (r *Reconciler) planWaves(ids []string) ([]wave, []wave) {
+	applies := make(map[int][]string)
+	prunes := make(map[int][]string)
+	prunable := r.canPrune()
+
+	for _, id := range ids {
+		if resource, err := r.input.Get(id); err == nil {
+			object := &unstructured.Unstructured{}
+			if err := object.UnmarshalJSON(resource.Content); err != nil {
+				applies[DefaultWave] = append(applies[DefaultWave], id)
+				continue
+			}
+			number := r.waveFor(object)
+			applies[number] = append(applies[number], id)
+			continue
+		}
+		if !prunable {
+			continue
+		}
+
+		number := DefaultWave
+		if live, err := r.getLiveObject(id); err == nil {
+			number = r.waveFor(live)
+		}
+		prunes[number] = append(prunes[number], id)
+	}
+
+	return sortWaves(applies), sortWaves(prunes)
+}
+
+func (r *Reconciler) waveFor(object *unstructured.Unstructured) int {
+	if value, found := object.GetAnnotations()[WaveAnnotation]; found {
+		number, err := strconv.Atoi(value)
+		if err == nil {
+			return number
+		}
+		r.logger.Warn().Err(err).Str("name", object.GetName()).Str("kind", object.GetKind()).Str("wave", value).Msg("Invalid wave annotation, using default")
+	}
+
+	if number, found := KindWaves[object.GetKind()]; found {
+		return number
+	}
+	return DefaultWave
+}
+
+// runWave reconciles all the resources in the wave concurrently, and returns true if none of them failed
+func (r *Reconciler) runWave(current wave) bool {
+	var group sync.WaitGroup
+	for _, id := range current.ids {
+		group.Add(1)
+		go func(id string) {
+			defer group.Done()
+			r.reconcile(id)
+		}(id)
+	}
+	group.Wait()
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, id := range current.ids {
+		if result, found := r.results[id]; found && result.Outcome == Failed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Reconciler) markAsPending(blocked wave, reason string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, id := range blocked.ids {
+		r.results[id] = Result{
+			Id:        id,
+			Outcome:   Pending,
+			Error:     reason,
+			DryRun:    r.dryRun,
+			Timestamp: time.Now(),
+		}
+	}
+}
+
+func sortWaves(grouped map[int][]string) []wave {
+	waves := make([]wave, 0, len(grouped))
+	for number, ids := range grouped {
+		sort.Strings(ids)
+		waves = append(waves, wave{number: number, ids: ids})
+	}
+	sort.Slice(waves, func(i, j int) bool {
+		return waves[i].number < waves[j].number
+	})
+	return waves
+}
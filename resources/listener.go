@@ -0,0 +1,7 @@
+package resources
+
+// Listener is notified when a resource in a repository is updated or removed.
+type Listener interface {
+	OnResourceUpdated(id string)
+	OnResourceRemoved(id string)
+}
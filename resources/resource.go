@@ -2,6 +2,11 @@ package resources
 
 // Resource defines a resource that Kokk can work with.
 type Resource struct {
-	Id      string
-	Content []byte
+	Id        string
+	Content   []byte
+	Files     []string
+	Parent    string
+	Revision  string
+	Source    string
+	Overrides []string
 }
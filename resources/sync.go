@@ -0,0 +1,36 @@
+package resources
+
+import "sync"
+
+// SyncSignal tells when a repository has synced. Until then, resources that are missing from the repository might just
+// not have been loaded yet.
+type SyncSignal struct {
+	synced chan struct{}
+	once   sync.Once
+}
+
+func NewSyncSignal() *SyncSignal {
+	return &SyncSignal{synced: make(chan struct{})}
+}
+
+// MarkSynced marks the repository as synced, and wakes up everyone waiting for it. Marking it again has no effect.
+func (s *SyncSignal) MarkSynced() {
+	s.once.Do(func() {
+		close(s.synced)
+	})
+}
+
+// Synced returns a channel that is closed when the repository has synced
+func (s *SyncSignal) Synced() <-chan struct{} {
+	return s.synced
+}
+
+// HasSynced returns true when the repository has synced
+func (s *SyncSignal) HasSynced() bool {
+	select {
+	case <-s.synced:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,7 @@
+package retry
+
+import "errors"
+
+var (
+	FailureNotFound = errors.New("failure not found")
+)
@@ -0,0 +1,12 @@
+package retry
+
+import "time"
+
+// Failure records the last error of an operation that is being retried, or that has been given up on.
+type Failure struct {
+	Key       string    `json:"key"`
+	Retries   int       `json:"retries"`
+	Error     string    `json:"error"`
+	GaveUp    bool      `json:"gaveUp"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,145 @@
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/knadh/koanf"
+	"github.com/rs/zerolog"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Processor processes a batch of keys taken from a Queue, and returns the errors of the keys that failed
+type Processor func(keys []string) map[string]error
+
+// GiveUpHandler is notified of the keys that a Queue gives up on
+type GiveUpHandler func(key string)
+
+// Queue is a rate-limited work queue of keys. Repeated additions of a key that is not yet processed are deduplicated,
+// and failed keys are retried with an exponential per-key backoff configured by 'retry.baseDelay' and
+// 'retry.maxDelay', until they have been retried 'retry.maxRetries' times.
+type Queue struct {
+	queue      workqueue.RateLimitingInterface
+	maxRetries int
+	failures   map[string]Failure
+	onGiveUp   GiveUpHandler
+	mutex      sync.RWMutex
+	logger     *zerolog.Logger
+}
+
+func NewQueue(config *koanf.Koanf, name string, logger *zerolog.Logger) *Queue {
+	loggerWithQueue := logger.With().Str("queue", name).Logger()
+
+	limiter := workqueue.NewItemExponentialFailureRateLimiter(config.Duration("retry.baseDelay"), config.Duration("retry.maxDelay"))
+
+	return &Queue{
+		queue:      workqueue.NewNamedRateLimitingQueue(limiter, name),
+		maxRetries: config.Int("retry.maxRetries"),
+		failures:   make(map[string]Failure),
+		logger:     &loggerWithQueue,
+	}
+}
+
+// Add queues the key for processing
+func (q *Queue) Add(key string) {
+	q.queue.Add(key)
+}
+
+func (q *Queue) GetFailure(key string) (*Failure, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if failure, found := q.failures[key]; found {
+		return &failure, nil
+	}
+
+	return nil, FailureNotFound
+}
+
+func (q *Queue) ListFailures() []Failure {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	list := make([]Failure, 0, len(q.failures))
+	for _, failure := range q.failures {
+		list = append(list, failure)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// OnGiveUp registers a GiveUpHandler that is called when a key has failed too many times. It must be registered before
+// the queue is run.
+func (q *Queue) OnGiveUp(handler GiveUpHandler) {
+	q.onGiveUp = handler
+}
+
+// Run processes the queued keys with the supplied Processor until the queue is shut down. All keys that are queued
+// when the Processor is invoked are passed to it as one batch.
+func (q *Queue) Run(process Processor) {
+	defer q.logger.Warn().Msg("Queue processing finished")
+
+	for {
+		item, shutdown := q.queue.Get()
+		if shutdown {
+			return
+		}
+
+		keys := []string{item.(string)}
+		for q.queue.Len() > 0 {
+			item, shutdown := q.queue.Get()
+			if shutdown {
+				break
+			}
+			keys = append(keys, item.(string))
+		}
+
+		errs := process(keys)
+		for _, key := range keys {
+			gaveUp := q.handle(key, errs[key])
+			q.queue.Done(key)
+			if gaveUp && q.onGiveUp != nil {
+				q.onGiveUp(key)
+			}
+		}
+	}
+}
+
+// ShutDown stops the queue, and makes Run return after processing the current batch
+func (q *Queue) ShutDown() {
+	q.queue.ShutDown()
+}
+
+// handle records the outcome of processing the key, and returns true if it failed too many times and was given up on
+func (q *Queue) handle(key string, err error) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err == nil {
+		q.queue.Forget(key)
+		delete(q.failures, key)
+		return false
+	}
+
+	failure := Failure{
+		Key:       key,
+		Retries:   q.queue.NumRequeues(key),
+		Error:     err.Error(),
+		Timestamp: time.Now(),
+	}
+
+	if failure.Retries < q.maxRetries {
+		q.logger.Debug().Err(err).Str("key", key).Int("retries", failure.Retries).Msg("Operation failed, retrying")
+		q.queue.AddRateLimited(key)
+	} else {
+		q.logger.Error().Err(err).Str("key", key).Int("retries", failure.Retries).Msg("Operation failed too many times, giving up")
+		q.queue.Forget(key)
+		failure.GaveUp = true
+	}
+
+	q.failures[key] = failure
+	return failure.GaveUp
+}